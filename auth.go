@@ -0,0 +1,152 @@
+package onvif
+
+import (
+	"context"
+	"net/url"
+)
+
+// AuthMode selects how credentials are sent to a device
+type AuthMode int
+
+const (
+	// AuthAuto tries the digest modes in turn until the device accepts one,
+	// and remembers it for the next requests to that device. The modes
+	// sending the password in clear text are only tried when the client
+	// allows it with AllowCleartextAuth.
+	AuthAuto AuthMode = iota
+	// AuthUsernameTokenDigest sends a WS-Security UsernameToken with a
+	// PasswordDigest.
+	AuthUsernameTokenDigest
+	// AuthUsernameTokenText sends a WS-Security UsernameToken with a
+	// PasswordText.
+	AuthUsernameTokenText
+	// AuthHTTPDigest uses HTTP Digest authentication.
+	AuthHTTPDigest
+	// AuthHTTPBasic uses HTTP Basic authentication.
+	AuthHTTPBasic
+)
+
+// authProbeOrder is the order in which AuthAuto tries the modes
+var authProbeOrder = []AuthMode{
+	AuthUsernameTokenDigest,
+	AuthHTTPDigest,
+}
+
+// cleartextProbeOrder is the order in which AuthAuto tries the modes sending
+// the password in clear text, after authProbeOrder, when they are allowed
+var cleartextProbeOrder = []AuthMode{
+	AuthUsernameTokenText,
+	AuthHTTPBasic,
+}
+
+// String implements the fmt.Stringer interface
+func (mode AuthMode) String() string {
+	switch mode {
+	case AuthAuto:
+		return "Auto"
+	case AuthUsernameTokenDigest:
+		return "UsernameTokenDigest"
+	case AuthUsernameTokenText:
+		return "UsernameTokenText"
+	case AuthHTTPDigest:
+		return "HTTPDigest"
+	case AuthHTTPBasic:
+		return "HTTPBasic"
+	}
+	return "AuthMode(" + intToString(int(mode)) + ")"
+}
+
+// usesToken reports whether the mode sends a WS-Security UsernameToken
+func (mode AuthMode) usesToken() bool {
+	return mode == AuthUsernameTokenDigest || mode == AuthUsernameTokenText
+}
+
+// AuthMode returns the authentication mode negotiated with the device at
+// xAddr, or AuthAuto if none has been negotiated yet.
+func (client *Client) AuthMode(xAddr string) AuthMode {
+	urlXAddr, err := url.Parse(xAddr)
+	if err != nil {
+		return AuthAuto
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.authModes[deviceKey(urlXAddr)]
+}
+
+func (client *Client) setAuthMode(xAddr *url.URL, mode AuthMode) {
+	client.mu.Lock()
+	client.authModes[deviceKey(xAddr)] = mode
+	client.mu.Unlock()
+}
+
+// sendAuthenticated sends the request with the given credentials and mode.
+// When the mode is AuthAuto, the negotiated mode of the device is used, or
+// the modes are probed until one is accepted. The cleartext modes are only
+// probed when the client allows them.
+func (soap SOAP) sendAuthenticated(ctx context.Context, client *Client, xAddr *url.URL) ([]byte, error) {
+	mode := soap.AuthMode
+	if mode == AuthAuto {
+		mode = client.DefaultAuthMode
+	}
+	if mode == AuthAuto {
+		client.mu.Lock()
+		mode = client.authModes[deviceKey(xAddr)]
+		client.mu.Unlock()
+	}
+	if mode != AuthAuto {
+		return soap.sendWithMode(ctx, client, xAddr, mode)
+	}
+
+	modes := authProbeOrder
+	if client.AllowCleartextAuth {
+		modes = append(append([]AuthMode(nil), authProbeOrder...), cleartextProbeOrder...)
+	}
+
+	var responseBody []byte
+	var err error
+	for _, mode := range modes {
+		responseBody, err = soap.sendWithMode(ctx, client, xAddr, mode)
+		if IsNotAuthorized(err) {
+			continue
+		}
+
+		// Any answer but an authentication failure means the device got
+		// past the authentication.
+		if err == nil || isDeviceError(err) {
+			client.setAuthMode(xAddr, mode)
+		}
+		return responseBody, err
+	}
+
+	return responseBody, err
+}
+
+// sendWithMode sends the request with the given mode. WS-Security tokens are
+// stamped with the device clock and the request is retried once with a fresh
+// measure when the device rejects the token because of its clock.
+func (soap SOAP) sendWithMode(ctx context.Context, client *Client, xAddr *url.URL, mode AuthMode) ([]byte, error) {
+	soap.AuthMode = mode
+	if !mode.usesToken() {
+		return soap.send(ctx, client, xAddr)
+	}
+
+	// The clock is told by the device service
+	clockXAddr := xAddr
+	if soap.deviceXAddr != "" {
+		if deviceXAddr, err := url.Parse(soap.deviceXAddr); err == nil {
+			clockXAddr = deviceXAddr
+		}
+	}
+
+	soap.clockOffset = client.clockOffset(ctx, clockXAddr)
+	responseBody, err := soap.send(ctx, client, xAddr)
+	if IsNotAuthorized(err) {
+		if offset, ok := client.resyncClock(ctx, clockXAddr, soap.clockOffset, err); ok {
+			soap.clockOffset = offset
+			responseBody, err = soap.send(ctx, client, xAddr)
+		}
+	}
+
+	return responseBody, err
+}
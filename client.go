@@ -0,0 +1,137 @@
+package onvif
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/quocson95/go-onvif/digest"
+)
+
+// Client holds the HTTP connection pool shared by the devices created from
+// it. For every camera it keeps one digest transport, so the digest
+// challenge of the first request is reused (with an incrementing nonce
+// count) by the following ones and steady-state calls cost a single request.
+// It also remembers the clock offset of every camera, which is applied to the
+// WS-Security tokens, and the authentication mode negotiated with it.
+//
+// A Client is safe for concurrent use and should be long-lived.
+type Client struct {
+	// DefaultAuthMode is used for devices whose AuthMode is AuthAuto.
+	// When it is AuthAuto too, the mode is negotiated with every device.
+	DefaultAuthMode AuthMode
+
+	// AllowCleartextAuth lets AuthAuto fall back to AuthUsernameTokenText
+	// and AuthHTTPBasic when the device rejects the digest modes. They send
+	// the password in clear text, readable by anyone on the path of plain
+	// http requests, so they are not tried by default.
+	AllowCleartextAuth bool
+
+	// RootCAs verifies the certificates of HTTPS devices. The system pool
+	// is used when it is nil.
+	RootCAs *x509.CertPool
+
+	// Certificates are presented to devices which ask for a client
+	// certificate.
+	Certificates []tls.Certificate
+
+	// InsecureSkipVerify accepts any certificate from HTTPS devices which
+	// have no pinned certificate. It should only be used for testing.
+	InsecureSkipVerify bool
+
+	// Logger receives the redacted SOAP envelopes and the other log
+	// entries of the devices using the client. Entries are discarded when
+	// it is nil.
+	Logger Logger
+
+	// Trace, when not nil, is called around every request sent with the
+	// client.
+	Trace *Trace
+
+	mu         sync.Mutex
+	dialer     *net.Dialer
+	transport  *http.Transport
+	pinned     map[string]pinnedTransport
+	transports map[string]*digest.Transport
+	offsets    map[string]time.Duration
+	clockSyncs map[string]*clockSync
+	authModes  map[string]AuthMode
+	pins       map[string][]byte
+}
+
+// DefaultClient is the Client used by devices which do not have one.
+var DefaultClient = NewClient()
+
+// NewClient creates a new Client with its own connection pool.
+// The TLS fields of the client may be set until it is first used.
+func NewClient() *Client {
+	client := &Client{
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		transports: make(map[string]*digest.Transport),
+		offsets:    make(map[string]time.Duration),
+		clockSyncs: make(map[string]*clockSync),
+		authModes:  make(map[string]AuthMode),
+		pins:       make(map[string][]byte),
+		pinned:     make(map[string]pinnedTransport),
+	}
+	return client
+}
+
+// NewDevice returns a Device bound to this client.
+func (client *Client) NewDevice(xAddr, user, password string) Device {
+	return Device{
+		XAddr:    xAddr,
+		User:     user,
+		Password: password,
+		Client:   client,
+	}
+}
+
+// CloseIdleConnections closes the idle connections of every camera and
+// forgets the cached digest challenges.
+func (client *Client) CloseIdleConnections() {
+	client.mu.Lock()
+	client.transports = make(map[string]*digest.Transport)
+	transports := []*http.Transport{client.transport}
+	for _, pinned := range client.pinned {
+		transports = append(transports, pinned.transport)
+	}
+	client.mu.Unlock()
+
+	for _, transport := range transports {
+		if transport != nil {
+			transport.CloseIdleConnections()
+		}
+	}
+}
+
+// deviceKey identifies a device by its scheme and host, so that every
+// service of the device shares the same state.
+func deviceKey(xAddr *url.URL) string {
+	return xAddr.Scheme + "://" + xAddr.Host
+}
+
+// roundTripper returns the digest transport for the camera at xAddr,
+// creating it on first use. It is shared by every user of the camera, the
+// credentials are given with each request by digest.WithCredentials so that
+// the client doesn't keep the passwords it was given.
+func (client *Client) roundTripper(xAddr *url.URL) http.RoundTripper {
+	key := deviceKey(xAddr)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	t, ok := client.transports[key]
+	if !ok {
+		t = &digest.Transport{Transport: roundTripperFunc(client.roundTrip)}
+		client.transports[key] = t
+	}
+	return t
+}
@@ -0,0 +1,374 @@
+package onvif
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testNotAuthorizedFault = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:ter="http://www.onvif.org/ver10/error">
+<env:Body><env:Fault>
+<env:Code><env:Value>env:Sender</env:Value><env:Subcode><env:Value>ter:NotAuthorized</env:Value></env:Subcode></env:Code>
+<env:Reason><env:Text xml:lang="en">Sender not Authorized</env:Text></env:Reason>
+</env:Fault></env:Body></env:Envelope>`
+
+// fakeCamera is a minimal device service whose clock is skewed from the
+// host clock and which rejects WS-Security tokens created too far from it.
+type fakeCamera struct {
+	mu       sync.Mutex
+	skew     time.Duration
+	requests []string
+}
+
+var createdPattern = regexp.MustCompile(`<Created[^>]*>([^<]+)</Created>`)
+
+func (camera *fakeCamera) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request := string(body)
+
+	camera.mu.Lock()
+	skew := camera.skew
+	camera.requests = append(camera.requests, request)
+	camera.mu.Unlock()
+
+	now := time.Now().Add(skew).UTC()
+	w.Header().Set("Content-Type", "application/soap+xml")
+	if strings.Contains(request, "GetSystemDateAndTime") {
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema"><s:Body>
+<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime>
+<tt:DateTimeType>NTP</tt:DateTimeType><tt:DaylightSavings>false</tt:DaylightSavings>
+<tt:UTCDateTime><tt:Time><tt:Hour>%d</tt:Hour><tt:Minute>%d</tt:Minute><tt:Second>%d</tt:Second></tt:Time>
+<tt:Date><tt:Year>%d</tt:Year><tt:Month>%d</tt:Month><tt:Day>%d</tt:Day></tt:Date></tt:UTCDateTime>
+</tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse></s:Body></s:Envelope>`,
+			now.Hour(), now.Minute(), now.Second(), now.Year(), int(now.Month()), now.Day())
+		return
+	}
+
+	var created time.Time
+	match := createdPattern.FindStringSubmatch(request)
+	if len(match) == 2 {
+		created, _ = time.Parse(time.RFC3339, strings.TrimSpace(match[1]))
+	}
+	if created.Sub(now) > 5*time.Second || now.Sub(created) > 5*time.Second {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(testNotAuthorizedFault))
+		return
+	}
+
+	w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>
+<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer><tds:Model>C1</tds:Model></tds:GetDeviceInformationResponse>
+</s:Body></s:Envelope>`))
+}
+
+func (camera *fakeCamera) setSkew(skew time.Duration) {
+	camera.mu.Lock()
+	camera.skew = skew
+	camera.requests = nil
+	camera.mu.Unlock()
+}
+
+func (camera *fakeCamera) requestCount() int {
+	camera.mu.Lock()
+	defer camera.mu.Unlock()
+	return len(camera.requests)
+}
+
+func TestClientClockSkew(t *testing.T) {
+	camera := &fakeCamera{}
+	camera.setSkew(time.Hour)
+	server := httptest.NewServer(camera)
+	defer server.Close()
+
+	device := NewClient().NewDevice(server.URL+"/onvif/device_service", "admin", "secret")
+
+	info, err := device.GetInformation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Manufacturer != "Acme" {
+		t.Errorf("unexpected manufacturer %q", info.Manufacturer)
+	}
+	if n := camera.requestCount(); n != 2 {
+		t.Errorf("expected clock sync and request, got %d requests", n)
+	}
+
+	// The camera clock jumps, the rejected request is retried once
+	defer func(interval time.Duration) { clockResyncInterval = interval }(clockResyncInterval)
+	clockResyncInterval = 0
+	camera.setSkew(-time.Hour)
+	if _, err = device.GetInformation(); err != nil {
+		t.Fatal(err)
+	}
+	if n := camera.requestCount(); n != 3 {
+		t.Errorf("expected rejected request, clock sync and retry, got %d requests", n)
+	}
+}
+
+func TestClientClockResync(t *testing.T) {
+	var mu sync.Mutex
+	var syncs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "GetSystemDateAndTime") {
+			mu.Lock()
+			syncs = append(syncs, r.URL.Path)
+			mu.Unlock()
+
+			// Let the concurrent requests wait for the same measure
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>
+<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime/></tds:GetSystemDateAndTimeResponse></s:Body></s:Envelope>`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(testNotAuthorizedFault))
+	}))
+	defer server.Close()
+
+	// The device service isn't at the usual path, wrong credentials cost a
+	// single measure of the clock
+	device := NewClient().NewDevice(server.URL+"/onvif/services", "admin", "wrong")
+	device.AuthMode = AuthUsernameTokenDigest
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := device.GetInformation(); !IsNotAuthorized(err) {
+				t.Errorf("expected NotAuthorized, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := device.GetInformation(); !IsNotAuthorized(err) {
+		t.Errorf("expected NotAuthorized, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(syncs) != 1 || syncs[0] != "/onvif/services" {
+		t.Errorf("got clock measures at %q, want one at /onvif/services", syncs)
+	}
+}
+
+func TestClientNegotiatesAuthMode(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	var cleartext bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		user, password, ok := r.BasicAuth()
+
+		mu.Lock()
+		requests++
+		cleartext = cleartext || ok || strings.Contains(string(body), "PasswordText")
+		mu.Unlock()
+
+		if !ok || user != "admin" || password != "secret" || strings.Contains(string(body), "UsernameToken") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="camera"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>
+<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer></tds:GetDeviceInformationResponse>
+</s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	// The cleartext modes are not tried by default
+	client := NewClient()
+	device := client.NewDevice(server.URL+"/onvif/device_service", "admin", "secret")
+	if _, err := device.GetInformation(); !IsNotAuthorized(err) {
+		t.Fatalf("expected NotAuthorized without cleartext modes, got %v", err)
+	}
+	if mode := client.AuthMode(device.XAddr); mode != AuthAuto {
+		t.Fatalf("negotiated %v without cleartext modes", mode)
+	}
+	mu.Lock()
+	if cleartext {
+		t.Error("password sent in clear text")
+	}
+	mu.Unlock()
+
+	client.AllowCleartextAuth = true
+	if _, err := device.GetInformation(); err != nil {
+		t.Fatal(err)
+	}
+	if mode := client.AuthMode(device.XAddr); mode != AuthHTTPBasic {
+		t.Fatalf("negotiated %v, want %v", mode, AuthHTTPBasic)
+	}
+
+	mu.Lock()
+	requests = 0
+	mu.Unlock()
+	if _, err := device.GetInformation(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("negotiated mode was not reused, got %d requests", requests)
+	}
+
+	// An explicit mode is not negotiated
+	device.AuthMode = AuthHTTPDigest
+	if _, err := device.GetInformation(); !IsNotAuthorized(err) {
+		t.Errorf("expected NotAuthorized with HTTP digest, got %v", err)
+	}
+}
+
+func TestClientDigestTransports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Digest realm="camera", nonce="abc", qop="auth"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	// Every password tried on the camera shares its transport, which doesn't
+	// keep them
+	client := NewClient()
+	for _, password := range []string{"admin", "12345", "secret"} {
+		device := client.NewDevice(server.URL+"/onvif/device_service", "admin", password)
+		device.AuthMode = AuthHTTPDigest
+		if _, err := device.GetInformation(); !IsNotAuthorized(err) {
+			t.Errorf("expected NotAuthorized, got %v", err)
+		}
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.transports) != 1 {
+		t.Fatalf("got %d transports, want 1", len(client.transports))
+	}
+	for _, transport := range client.transports {
+		if transport.Username != "" || transport.Password != "" {
+			t.Errorf("transport keeps the credentials %q:%q", transport.Username, transport.Password)
+		}
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>
+<tds:GetDiscoveryModeResponse><tds:DiscoveryMode>Discoverable</tds:DiscoveryMode></tds:GetDiscoveryModeResponse>
+</s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	xAddr := server.URL + "/onvif/device_service"
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	// The self-signed certificate is rejected by default
+	if _, err := NewClient().NewDevice(xAddr, "", "").GetDiscoveryMode(); err == nil {
+		t.Error("expected certificate error")
+	}
+
+	// Trusted through a custom CA pool
+	client := NewClient()
+	client.RootCAs = x509.NewCertPool()
+	client.RootCAs.AddCert(server.Certificate())
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); err != nil {
+		t.Errorf("custom CA pool: %v", err)
+	}
+
+	// Trusted through its pinned fingerprint
+	client = NewClient()
+	client.PinCertificate(host, CertificateFingerprint(server.Certificate()))
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); err != nil {
+		t.Errorf("pinned certificate: %v", err)
+	}
+
+	// A new pin applies to the following connections
+	client.PinCertificate(host, strings.Repeat("00", 32))
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); !errors.Is(err, ErrCertificatePin) {
+		t.Errorf("new pin: expected ErrCertificatePin, got %v", err)
+	}
+
+	// A wrong pin is rejected, even in insecure mode
+	client = NewClient()
+	client.InsecureSkipVerify = true
+	client.PinCertificate(host, strings.Repeat("00", 32))
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); !errors.Is(err, ErrCertificatePin) {
+		t.Errorf("expected ErrCertificatePin, got %v", err)
+	}
+}
+
+func TestClientTLSProxy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>
+<tds:GetDiscoveryModeResponse><tds:DiscoveryMode>Discoverable</tds:DiscoveryMode></tds:GetDiscoveryModeResponse>
+</s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	// The proxy tunnels the connections with CONNECT
+	var mu sync.Mutex
+	var tunnels int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tunnels++
+		mu.Unlock()
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	defer func(proxy func(*http.Request) (*url.URL, error)) { environmentProxy = proxy }(environmentProxy)
+	environmentProxy = http.ProxyURL(proxyURL)
+
+	xAddr := server.URL + "/onvif/device_service"
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	// The certificate is checked inside the tunnel
+	if _, err := NewClient().NewDevice(xAddr, "", "").GetDiscoveryMode(); err == nil {
+		t.Error("expected certificate error")
+	}
+
+	// The custom settings are applied through the proxy too
+	client := NewClient()
+	client.RootCAs = x509.NewCertPool()
+	client.RootCAs.AddCert(server.Certificate())
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); err != nil {
+		t.Errorf("custom CA pool: %v", err)
+	}
+	client = NewClient()
+	client.PinCertificate(host, CertificateFingerprint(server.Certificate()))
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); err != nil {
+		t.Errorf("pinned certificate: %v", err)
+	}
+	client = NewClient()
+	client.InsecureSkipVerify = true
+	client.PinCertificate(host, strings.Repeat("00", 32))
+	if _, err := client.NewDevice(xAddr, "", "").GetDiscoveryMode(); !errors.Is(err, ErrCertificatePin) {
+		t.Errorf("expected ErrCertificatePin, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if tunnels != 4 {
+		t.Errorf("got %d tunnels, want 4", tunnels)
+	}
+}
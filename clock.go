@@ -0,0 +1,164 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// clockResyncThreshold is how far the device clock must have drifted from
+// the cached offset for a NotAuthorized fault to be blamed on the clock.
+// Devices report their time with a one second resolution.
+const clockResyncThreshold = 2 * time.Second
+
+// clockResyncInterval is how long a measure of the device clock is reused
+// before a NotAuthorized fault triggers another one, replaced by the tests
+var clockResyncInterval = time.Minute
+
+// UTC returns the UTC date and time reported by the device, or the zero time
+// when the device did not report its date.
+func (systemDT SystemDateAndTime) UTC() time.Time {
+	if systemDT.Year == 0 {
+		return time.Time{}
+	}
+	return time.Date(systemDT.Year, time.Month(systemDT.Month), systemDT.Day,
+		systemDT.Hour, systemDT.Minute, systemDT.Second, 0, time.UTC)
+}
+
+// clockOffset returns the cached clock offset of the device whose device
+// service is at xAddr, measuring it on first use.
+func (client *Client) clockOffset(ctx context.Context, xAddr *url.URL) time.Duration {
+	client.mu.Lock()
+	offset, ok := client.offsets[deviceKey(xAddr)]
+	client.mu.Unlock()
+	if ok {
+		return offset
+	}
+
+	offset, _ = client.syncClock(ctx, xAddr)
+	return offset
+}
+
+// clockSync is a measure of the clock of a device, shared by the requests
+// needing it at the same time and reused for clockResyncInterval
+type clockSync struct {
+	done   chan struct{}
+	offset time.Duration
+	err    error
+
+	// at is when the measure completed, guarded by the mutex of the client
+	at time.Time
+}
+
+// syncClock measures the offset between the clock of the device whose
+// device service is at xAddr and the local clock, and caches it. Concurrent
+// calls share the same measure, and a measure is reused for
+// clockResyncInterval so that a device rejecting the credentials is not
+// asked its time on every request.
+func (client *Client) syncClock(ctx context.Context, xAddr *url.URL) (time.Duration, error) {
+	key := deviceKey(xAddr)
+
+	client.mu.Lock()
+	measure, ok := client.clockSyncs[key]
+	if ok && !measure.at.IsZero() && time.Since(measure.at) >= clockResyncInterval {
+		ok = false
+	}
+	if !ok {
+		measure = &clockSync{done: make(chan struct{})}
+		client.clockSyncs[key] = measure
+	}
+	client.mu.Unlock()
+
+	if ok {
+		select {
+		case <-measure.done:
+			return measure.offset, measure.err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	measure.offset, measure.err = client.measureClock(ctx, xAddr)
+	client.mu.Lock()
+	measure.at = time.Now()
+	if ctx.Err() != nil && client.clockSyncs[key] == measure {
+		// The measure was cut short, the next request measures again
+		delete(client.clockSyncs, key)
+	}
+	client.mu.Unlock()
+	close(measure.done)
+
+	return measure.offset, measure.err
+}
+
+// measureClock asks the device its time with an unauthenticated
+// GetSystemDateAndTime, and caches the offset from the local clock.
+func (client *Client) measureClock(ctx context.Context, xAddr *url.URL) (time.Duration, error) {
+	device := Device{
+		XAddr:  xAddr.String(),
+		Client: client,
+	}
+
+	sent := time.Now()
+	systemDT, err := device.GetSystemDateAndTimeContext(ctx)
+	if err != nil {
+		// Don't ask again on every request if the device can't tell its time
+		if ctx.Err() == nil {
+			client.setClockOffset(xAddr, 0)
+		}
+		return 0, err
+	}
+
+	var offset time.Duration
+	if deviceTime := systemDT.UTC(); !deviceTime.IsZero() {
+		// Assume the device read its clock half way through the round trip
+		received := time.Now()
+		offset = deviceTime.Sub(sent.Add(received.Sub(sent) / 2))
+	}
+	client.setClockOffset(xAddr, offset)
+
+	return offset, nil
+}
+
+func (client *Client) setClockOffset(xAddr *url.URL, offset time.Duration) {
+	client.mu.Lock()
+	client.offsets[deviceKey(xAddr)] = offset
+	client.mu.Unlock()
+}
+
+// resyncClock measures the device clock again after the device rejected a
+// token stamped with the used offset. It returns the new offset and whether
+// the request is worth retrying, that is if the offset changed and the clock
+// has drifted or the fault mentions the token time. The offset doesn't
+// change when the last measure is reused.
+func (client *Client) resyncClock(ctx context.Context, xAddr *url.URL, used time.Duration, fault error) (time.Duration, bool) {
+	offset, err := client.syncClock(ctx, xAddr)
+	if err != nil || offset == used {
+		return used, false
+	}
+
+	drift := offset - used
+	if drift < 0 {
+		drift = -drift
+	}
+	return offset, drift > clockResyncThreshold || isClockFault(fault)
+}
+
+// isClockFault reports whether a NotAuthorized fault points to a mismatch
+// between the token time and the device clock.
+func isClockFault(err error) bool {
+	var fault *SOAPFault
+	if !errors.As(err, &fault) {
+		return false
+	}
+
+	text := strings.ToLower(fault.Reason + " " + fault.Detail)
+	for _, hint := range []string{"time", "clock", "expired", "created"} {
+		if strings.Contains(text, hint) {
+			return true
+		}
+	}
+	return false
+}
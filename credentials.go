@@ -0,0 +1,252 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults of CredentialChecker
+const (
+	defaultMaxAttempts = 3
+	defaultBackoff     = time.Second
+)
+
+// ErrAttemptLimit is reported for the devices to which MaxAttempts
+// authenticated requests were sent before all the credentials could be
+// tried
+var ErrAttemptLimit = errors.New("onvif: credential attempt limit reached")
+
+// CredentialChecker tries a list of site credentials on discovered devices
+// to find the ones each device accepts. Cameras often lock an account after
+// a few failed logins, so the attempts on a device are limited and spaced
+// out.
+type CredentialChecker struct {
+	// Credentials are tried in order on every device, until one is
+	// accepted
+	Credentials []Credentials
+
+	// MaxAttempts is the number of authenticated requests after which a
+	// device is left alone, 3 when zero. Every request sent with
+	// credentials counts, including the retries after a clock
+	// resynchronization.
+	MaxAttempts int
+
+	// Backoff is the delay before trying a device again after it rejected
+	// credentials, doubled after every rejection. It is 1 second when zero.
+	Backoff time.Duration
+
+	// AuthMode is the authentication used for the attempts,
+	// AuthUsernameTokenDigest when it is AuthAuto, which would send a
+	// request per mode. AuthUsernameTokenText and AuthHTTPBasic send every
+	// candidate password in clear text to hosts which merely answered a
+	// discovery Probe, and which may be impersonated, so they should only
+	// be used on trusted networks or over HTTPS.
+	AuthMode AuthMode
+
+	// Workers is the number of devices checked concurrently, 4 when zero
+	Workers int
+
+	// Client sends the requests, DefaultClient when nil
+	Client *Client
+}
+
+// CredentialAttempt is a GetDeviceInformation sent with the credentials at
+// Index in CredentialChecker.Credentials. Passwords are left out of the
+// attempts.
+type CredentialAttempt struct {
+	Index int
+	User  string
+	XAddr string
+
+	// Err is nil when the credentials were accepted, and matches
+	// ErrNotAuthorized when they were rejected
+	Err error
+}
+
+// CredentialReport is the result of the check of a device
+type CredentialReport struct {
+	Discovered DiscoveredDevice
+
+	// Authorized tells whether the device accepted some credentials
+	Authorized bool
+
+	// Device is the device with the accepted credentials, password
+	// included. It is left out of the JSON encoding of the report, so that
+	// logging or storing reports doesn't leak the password.
+	Device Device `json:"-"`
+
+	// Information is the answer to the accepted GetDeviceInformation
+	Information DeviceInformation
+
+	// Attempts are the requests sent to the device, in order
+	Attempts []CredentialAttempt
+
+	// Err tells why no credentials were accepted. It matches
+	// ErrNotAuthorized when the device rejected all of them, and is
+	// ErrAttemptLimit when the limit was reached first.
+	Err error
+}
+
+// Check tries the credentials on every device, Workers devices at a time,
+// and returns a report for every device in the order of devices
+func (checker CredentialChecker) Check(devices []DiscoveredDevice) []CredentialReport {
+	return checker.CheckContext(context.Background(), devices)
+}
+
+// CheckContext is like Check but uses ctx for the requests.
+func (checker CredentialChecker) CheckContext(ctx context.Context, devices []DiscoveredDevice) []CredentialReport {
+	reports := make([]CredentialReport, len(devices))
+	forEachDevice(checker.Workers, len(devices), func(index int) {
+		reports[index] = checker.check(ctx, devices[index])
+	})
+	return reports
+}
+
+// check tries the credentials on a single device. A rejection is followed
+// by the next credentials after the backoff, and a network error by the
+// same credentials on the next XAddr of the device. Requests which don't
+// reach the device don't count against MaxAttempts.
+func (checker CredentialChecker) check(ctx context.Context, discovered DiscoveredDevice) CredentialReport {
+	report := CredentialReport{Discovered: discovered}
+	if len(discovered.XAddrs) == 0 {
+		report.Err = errNoXAddr
+		return report
+	}
+
+	maxAttempts := checker.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	backoff := checker.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	device := discovered.Device()
+	device.AuthMode = checker.AuthMode
+	if device.AuthMode == AuthAuto {
+		device.AuthMode = AuthUsernameTokenDigest
+	}
+	device.Client = checker.Client
+
+	ctx, budget := withLoginBudget(ctx, maxAttempts)
+	xAddr := 0
+	for index := 0; index < len(checker.Credentials); {
+		if budget.exhausted() {
+			report.Err = ErrAttemptLimit
+			return report
+		}
+
+		credentials := checker.Credentials[index]
+		device.XAddr = discovered.XAddrs[xAddr]
+		device.User, device.Password = credentials.User, credentials.Password
+		information, err := device.GetInformationContext(ctx)
+		report.Attempts = append(report.Attempts, CredentialAttempt{
+			Index: index,
+			User:  credentials.User,
+			XAddr: device.XAddr,
+			Err:   err,
+		})
+
+		switch {
+		case err == nil:
+			report.Authorized = true
+			report.Device = device
+			report.Information = information
+			return report
+
+		case errors.Is(err, ErrAttemptLimit):
+			report.Err = ErrAttemptLimit
+			return report
+
+		case IsNotAuthorized(err):
+			device.logger().Log(LevelInfo, "credentials rejected", "xaddr", device.XAddr, "user", credentials.User)
+			report.Err = err
+			index++
+			if index == len(checker.Credentials) || budget.exhausted() {
+				continue
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				report.Err = ctx.Err()
+				return report
+			}
+			backoff *= 2
+
+		case isDeviceError(err) || ctx.Err() != nil:
+			report.Err = err
+			return report
+
+		default:
+			// The device can't be reached on this XAddr
+			report.Err = err
+			xAddr++
+			if xAddr == len(discovered.XAddrs) {
+				return report
+			}
+		}
+	}
+
+	return report
+}
+
+// loginBudget limits the requests sent with credentials to a device, see
+// withLoginBudget
+type loginBudget struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+// loginBudgetKey is the context key of a *loginBudget
+type loginBudgetKey struct{}
+
+// withLoginBudget returns a context in which at most max requests are sent
+// with credentials. The requests beyond fail with ErrAttemptLimit.
+func withLoginBudget(ctx context.Context, max int) (context.Context, *loginBudget) {
+	budget := &loginBudget{remaining: max}
+	return context.WithValue(ctx, loginBudgetKey{}, budget), budget
+}
+
+// loginBudgetOf returns the budget of ctx, or nil when it has none
+func loginBudgetOf(ctx context.Context) *loginBudget {
+	budget, _ := ctx.Value(loginBudgetKey{}).(*loginBudget)
+	return budget
+}
+
+// take counts a request, it reports false when the budget is exhausted. A
+// nil budget is unlimited.
+func (budget *loginBudget) take() bool {
+	if budget == nil {
+		return true
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	if budget.remaining <= 0 {
+		return false
+	}
+	budget.remaining--
+	return true
+}
+
+// refund gives back a request which didn't reach the device
+func (budget *loginBudget) refund() {
+	if budget == nil {
+		return
+	}
+	budget.mu.Lock()
+	budget.remaining++
+	budget.mu.Unlock()
+}
+
+// exhausted reports whether no more requests can be sent
+func (budget *loginBudget) exhausted() bool {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	return budget.remaining <= 0
+}
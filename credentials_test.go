@@ -0,0 +1,137 @@
+package onvif
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCredentialChecker(t *testing.T) {
+	server := fakeEnrichedCamera(t)
+	defer server.Close()
+
+	camera := DiscoveredDevice{ID: "camera", XAddrs: []string{"http://127.0.0.1:1/onvif/device_service", server.URL + "/onvif/device_service"}}
+	checker := CredentialChecker{
+		Credentials: []Credentials{{User: "admin", Password: "admin"}, {User: "admin", Password: "1234"}, {User: "admin", Password: "secret"}},
+		Backoff:     20 * time.Millisecond,
+		AuthMode:    AuthHTTPBasic,
+		Client:      NewClient(),
+	}
+
+	start := time.Now()
+	reports := checker.Check([]DiscoveredDevice{camera, {ID: "no-address"}})
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("attempts were not spaced out, took %v", elapsed)
+	}
+
+	report := reports[0]
+	if !report.Authorized || report.Device.Password != "secret" || report.Information.Manufacturer != "Acme" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if encoded, err := json.Marshal(report); err != nil || strings.Contains(string(encoded), "secret") {
+		t.Errorf("password in the JSON report %s, %v", encoded, err)
+	}
+	// The unreachable XAddr costs an attempt but no credentials
+	if len(report.Attempts) != 4 || report.Attempts[0].Err == nil || report.Attempts[1].Index != 0 || !IsNotAuthorized(report.Attempts[1].Err) {
+		t.Errorf("unexpected attempts %+v", report.Attempts)
+	}
+	if last := report.Attempts[3]; last.Index != 2 || last.User != "admin" || last.Err != nil || last.XAddr != camera.XAddrs[1] {
+		t.Errorf("unexpected accepted attempt %+v", last)
+	}
+	if reports[1].Authorized || reports[1].Err != errNoXAddr {
+		t.Errorf("unexpected report for a device without XAddr %+v", reports[1])
+	}
+
+	// The attempts stop at the limit, before the last credentials
+	checker.MaxAttempts = 2
+	report = checker.Check([]DiscoveredDevice{camera})[0]
+	if report.Authorized || report.Err != ErrAttemptLimit || len(report.Attempts) != 3 {
+		t.Errorf("got %d attempts and error %v, want the limit after 2 rejections", len(report.Attempts), report.Err)
+	}
+
+	checker.Credentials = checker.Credentials[:2]
+	report = checker.Check([]DiscoveredDevice{camera})[0]
+	if report.Authorized || !IsNotAuthorized(report.Err) {
+		t.Errorf("got error %v, want not authorized", report.Err)
+	}
+}
+
+func TestCredentialCheckerAuthAuto(t *testing.T) {
+	server := fakeEnrichedCamera(t)
+	defer server.Close()
+
+	var mu sync.Mutex
+	var modes []AuthMode
+	client := NewClient()
+	client.AllowCleartextAuth = true
+	client.Trace = &Trace{RequestDone: func(ctx context.Context, request TraceRequest, response TraceResponse) {
+		if request.Operation == "GetDeviceInformation" {
+			mu.Lock()
+			modes = append(modes, request.AuthMode)
+			mu.Unlock()
+		}
+	}}
+
+	// AuthAuto would probe the modes, it is replaced by the token digest
+	checker := CredentialChecker{
+		Credentials: []Credentials{{User: "admin", Password: "admin"}, {User: "admin", Password: "secret"}},
+		Backoff:     time.Millisecond,
+		Client:      client,
+	}
+	report := checker.Check([]DiscoveredDevice{{ID: "camera", XAddrs: []string{server.URL + "/onvif/device_service"}}})[0]
+	if report.Authorized || !IsNotAuthorized(report.Err) {
+		t.Errorf("got %+v, want the rejection of the token digest", report)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(modes) == 0 || len(modes) > defaultMaxAttempts {
+		t.Errorf("sent %d requests, want at most %d", len(modes), defaultMaxAttempts)
+	}
+	for _, mode := range modes {
+		if mode != AuthUsernameTokenDigest {
+			t.Errorf("request sent with %v", mode)
+		}
+	}
+}
+
+func TestLoginBudget(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("WWW-Authenticate", `Basic realm="camera"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	ctx, budget := withLoginBudget(context.Background(), 1)
+
+	// Requests which don't reach the device are given back
+	unreachable := Device{XAddr: "http://127.0.0.1:1/onvif/device_service", User: "admin", Password: "admin", AuthMode: AuthHTTPBasic, Client: NewClient()}
+	if _, err := unreachable.GetInformationContext(ctx); err == nil || budget.exhausted() {
+		t.Fatalf("got %v, budget exhausted %v", err, budget.exhausted())
+	}
+
+	device := Device{XAddr: server.URL, User: "admin", Password: "admin", AuthMode: AuthHTTPBasic, Client: NewClient()}
+	if _, err := device.GetInformationContext(ctx); !IsNotAuthorized(err) {
+		t.Fatalf("got %v, want not authorized", err)
+	}
+	if _, err := device.GetInformationContext(ctx); !errors.Is(err, ErrAttemptLimit) {
+		t.Fatalf("got %v, want ErrAttemptLimit", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 1 {
+		t.Errorf("device got %d requests, want 1", requests)
+	}
+}
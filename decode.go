@@ -0,0 +1,180 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// errEmptyBody is returned when a response has no element in its body
+var errEmptyBody = errors.New("onvif: empty SOAP body")
+
+// decodeResponse decodes the first element in the body of a SOAP envelope
+// into response. Elements are matched by name, so a field decodes the same
+// way whether the device sent it once or as a list. Values which don't fit
+// the type of their field, such as a malformed number, are errors.
+func decodeResponse(body []byte, response interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return errEmptyBody
+		}
+		if err != nil {
+			return err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if inBody {
+				if err := decoder.DecodeElement(response, &element); err != nil {
+					return fmt.Errorf("onvif: decoding %s: %w", element.Name.Local, err)
+				}
+				return nil
+			}
+			inBody = element.Name.Local == "Body"
+		case xml.EndElement:
+			if inBody {
+				return errEmptyBody
+			}
+		}
+	}
+}
+
+// xmlElement holds an element whose content is not known in advance
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Text     string       `xml:",chardata"`
+	Children []xmlElement `xml:",any"`
+}
+
+// bools returns the children of the element holding a boolean, keyed by
+// their local name. Children without a text, like extensions, are skipped.
+func (element xmlElement) bools() (map[string]bool, error) {
+	result := make(map[string]bool)
+	for _, child := range element.Children {
+		text := strings.TrimSpace(child.Text)
+		if text == "" || len(child.Children) > 0 {
+			continue
+		}
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("onvif: decoding %s: %w", child.XMLName.Local, err)
+		}
+		result[child.XMLName.Local] = value
+	}
+	return result, nil
+}
+
+// UnmarshalXML decodes a resolution given either with Width and Height
+// elements or with width and height attributes, as in Bounds.
+func (bounds *MediaBounds) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Width      int `xml:"Width"`
+		Height     int `xml:"Height"`
+		AttrWidth  int `xml:"width,attr"`
+		AttrHeight int `xml:"height,attr"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	*bounds = MediaBounds{Width: raw.Width, Height: raw.Height}
+	if bounds.Width == 0 && bounds.Height == 0 {
+		bounds.Width, bounds.Height = raw.AttrWidth, raw.AttrHeight
+	}
+	return nil
+}
+
+// UnmarshalXML decodes a video encoder configuration, whose encoding is an
+// attribute in the media2 service.
+func (config *VideoEncoderConfig) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain VideoEncoderConfig
+	var raw struct {
+		plain
+		EncodingAttr string `xml:"encoding,attr"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	*config = VideoEncoderConfig(raw.plain)
+	if config.Encoding == "" {
+		config.Encoding = raw.EncodingAttr
+	}
+	return nil
+}
+
+// UnmarshalXML decodes a rectangle, whose bounds are sent as floats. Bounds
+// with a fraction, such as normalized coordinates, are errors rather than
+// being truncated.
+func (rectangle *Rectangle) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Top    float64 `xml:"top,attr"`
+		Bottom float64 `xml:"bottom,attr"`
+		Left   float64 `xml:"left,attr"`
+		Right  float64 `xml:"right,attr"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	var err error
+	var bounds Rectangle
+	for _, bound := range []struct {
+		name  string
+		value float64
+		field *int
+	}{
+		{"top", raw.Top, &bounds.Top},
+		{"bottom", raw.Bottom, &bounds.Bottom},
+		{"left", raw.Left, &bounds.Left},
+		{"right", raw.Right, &bounds.Right},
+	} {
+		if *bound.field, err = integral(bound.name, bound.value); err != nil {
+			return err
+		}
+	}
+	*rectangle = bounds
+	return nil
+}
+
+// UnmarshalXML decodes a point, whose coordinates are sent as floats.
+// Coordinates with a fraction, such as normalized coordinates, are errors
+// rather than being truncated.
+func (point *Point) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		X float64 `xml:"x,attr"`
+		Y float64 `xml:"y,attr"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+
+	x, err := integral("x", raw.X)
+	if err != nil {
+		return err
+	}
+	y, err := integral("y", raw.Y)
+	if err != nil {
+		return err
+	}
+	*point = Point{X: x, Y: y}
+	return nil
+}
+
+// integral returns value as an int, or an error if it has a fraction or
+// doesn't fit an int
+func integral(name string, value float64) (int, error) {
+	if value != math.Trunc(value) || value > math.MaxInt32 || value < math.MinInt32 {
+		return 0, fmt.Errorf("onvif: %s is not an integer: %v", name, value)
+	}
+	return int(value), nil
+}
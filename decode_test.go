@@ -0,0 +1,192 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// serveBody starts a server answering every request with body in a SOAP envelope
+func serveBody(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"
+	xmlns:tt="http://www.onvif.org/ver10/schema"
+	xmlns:tds="http://www.onvif.org/ver10/device/wsdl"
+	xmlns:tev="http://www.onvif.org/ver10/events/wsdl"
+	xmlns:tse="http://www.onvif.org/ver10/search/wsdl"
+	xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2">
+	<env:Body>` + body + `</env:Body>
+</env:Envelope>`))
+	}))
+}
+
+func TestDecodeSingleAndListAlike(t *testing.T) {
+	const item = `<tt:SimpleItem Name="State" Value="true"/>`
+	pull := func(items string) string {
+		return `<tev:PullMessagesResponse>
+			<wsnt:NotificationMessage>
+				<wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:Device/Trigger/DigitalInput</wsnt:Topic>
+				<wsnt:Message>
+					<tt:Message UtcTime="2020-01-02T03:04:05Z">
+						<tt:Source><tt:SimpleItem Name="InputToken" Value="DI_0"/></tt:Source>
+						<tt:Data>` + items + `</tt:Data>
+					</tt:Message>
+				</wsnt:Message>
+			</wsnt:NotificationMessage>
+		</tev:PullMessagesResponse>`
+	}
+
+	server := serveBody(pull(item))
+	defer server.Close()
+	single, err := Device{}.PullMessages(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server = serveBody(pull(item + item))
+	defer server.Close()
+	list, err := Device{}.PullMessages(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := NotificationMessage{
+		Topic:   "tns1:Device/Trigger/DigitalInput",
+		UtcTime: "2020-01-02T03:04:05Z",
+		Source:  []MessageData{{Name: "InputToken", Value: "DI_0"}},
+		Data:    []MessageData{{Name: "State", Value: "true"}},
+	}
+	if len(single) != 1 || !reflect.DeepEqual(single[0], want) {
+		t.Errorf("single item decoded as %+v, want %+v", single, want)
+	}
+	want.Data = append(want.Data, want.Data[0])
+	if len(list) != 1 || !reflect.DeepEqual(list[0], want) {
+		t.Errorf("list of items decoded as %+v, want %+v", list, want)
+	}
+
+	const track = `<tt:Track><tt:TrackToken>VIDEO001</tt:TrackToken><tt:TrackType>Video</tt:TrackType></tt:Track>`
+	search := func(tracks string) string {
+		return `<tse:GetRecordingSearchResultsResponse>
+			<tse:ResultList>
+				<tt:SearchState>Completed</tt:SearchState>
+				<tt:RecordingInformation>
+					<tt:RecordingToken>REC001</tt:RecordingToken>` + tracks + `
+				</tt:RecordingInformation>
+			</tse:ResultList>
+		</tse:GetRecordingSearchResultsResponse>`
+	}
+
+	for count, tracks := range []string{track, track + track} {
+		server := serveBody(search(tracks))
+		defer server.Close()
+		result, err := Device{XAddr: server.URL}.GetRecordingSearchResults("token")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.RecordingInformation) != 1 {
+			t.Fatalf("got %d recordings, want 1", len(result.RecordingInformation))
+		}
+		if got := result.RecordingInformation[0].Track; len(got) != count+1 || got[count].TrackToken != "VIDEO001" {
+			t.Errorf("tracks decoded as %+v, want %d", got, count+1)
+		}
+	}
+}
+
+func TestDecodeMalformedNumber(t *testing.T) {
+	server := serveBody(`<tds:GetNetworkProtocolsResponse>
+		<tds:NetworkProtocols>
+			<tt:Name>HTTP</tt:Name>
+			<tt:Enabled>true</tt:Enabled>
+			<tt:Port>eighty</tt:Port>
+		</tds:NetworkProtocols>
+	</tds:GetNetworkProtocolsResponse>`)
+	defer server.Close()
+
+	if _, err := (Device{XAddr: server.URL}).GetNetworkProtocols(); err == nil {
+		t.Error("expected error for malformed port")
+	}
+}
+
+func TestDecodeFractionalCoordinates(t *testing.T) {
+	var rectangle Rectangle
+	if err := xml.Unmarshal([]byte(`<Window top="0" bottom="480" left="0" right="640.0"/>`), &rectangle); err != nil {
+		t.Fatal(err)
+	}
+	if rectangle != (Rectangle{Top: 0, Bottom: 480, Left: 0, Right: 640}) {
+		t.Errorf("unexpected rectangle %+v", rectangle)
+	}
+	if err := xml.Unmarshal([]byte(`<Window top="0" bottom="0.5" left="0" right="1"/>`), &rectangle); err == nil {
+		t.Errorf("fractional bound decoded as %+v", rectangle)
+	}
+
+	var point Point
+	if err := xml.Unmarshal([]byte(`<Point x="12" y="-3"/>`), &point); err != nil || point != (Point{X: 12, Y: -3}) {
+		t.Errorf("got %+v, %v", point, err)
+	}
+	if err := xml.Unmarshal([]byte(`<Point x="0.25" y="1"/>`), &point); err == nil {
+		t.Errorf("fractional coordinate decoded as %+v", point)
+	}
+}
+
+func TestDecodeWrongResponse(t *testing.T) {
+	server := serveBody(`<tds:GetHostnameResponse>
+		<tds:HostnameInformation><tt:Name>camera</tt:Name></tds:HostnameInformation>
+	</tds:GetHostnameResponse>`)
+	defer server.Close()
+
+	if _, err := (Device{XAddr: server.URL}).GetInformation(); err == nil {
+		t.Error("expected error for unexpected response element")
+	}
+
+	empty := serveBody(``)
+	defer empty.Close()
+	if _, err := (Device{XAddr: empty.URL}).GetHostname(); err != errEmptyBody {
+		t.Errorf("got %v for empty body, want %v", err, errEmptyBody)
+	}
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	server := serveBody(`<tds:GetCapabilitiesResponse>
+		<tds:Capabilities>
+			<tt:Device>
+				<tt:Network>
+					<tt:IPFilter>false</tt:IPFilter>
+					<tt:ZeroConfiguration>true</tt:ZeroConfiguration>
+				</tt:Network>
+			</tt:Device>
+			<tt:Events>
+				<tt:XAddr>http://camera/onvif/events</tt:XAddr>
+				<tt:WSPullPointSupport>true</tt:WSPullPointSupport>
+				<tt:WSSubscriptionPolicySupport>false</tt:WSSubscriptionPolicySupport>
+			</tt:Events>
+			<tt:Media>
+				<tt:XAddr>http://camera/onvif/media</tt:XAddr>
+				<tt:StreamingCapabilities>
+					<tt:RTP_RTSP_TCP>true</tt:RTP_RTSP_TCP>
+				</tt:StreamingCapabilities>
+			</tt:Media>
+		</tds:Capabilities>
+	</tds:GetCapabilitiesResponse>`)
+	defer server.Close()
+
+	capabilities, err := Device{XAddr: server.URL}.GetCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !capabilities.Network.ZeroConfig || capabilities.Media.XAddr != "http://camera/onvif/media" {
+		t.Errorf("unexpected capabilities %+v", capabilities)
+	}
+	if capabilities.EventsCap.XAddr != "http://camera/onvif/events" {
+		t.Errorf("events XAddr %q", capabilities.EventsCap.XAddr)
+	}
+	if want := map[string]bool{"PullPointSupport": true, "SubscriptionPolicySupport": false}; !reflect.DeepEqual(capabilities.EventsCap.Events, want) {
+		t.Errorf("events %v, want %v", capabilities.EventsCap.Events, want)
+	}
+	if want := map[string]bool{"RTP RTSP TCP": true}; !reflect.DeepEqual(capabilities.Streaming, want) {
+		t.Errorf("streaming %v, want %v", capabilities.Streaming, want)
+	}
+}
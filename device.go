@@ -1,7 +1,8 @@
 package onvif
 
 import (
-	"github.com/golang/glog"
+	"context"
+	"encoding/xml"
 	"strings"
 )
 
@@ -12,113 +13,89 @@ var deviceXMLNs = []string{
 
 // GetInformation fetch information of ONVIF camera
 func (device Device) GetInformation() (DeviceInformation, error) {
+	return device.GetInformationContext(context.Background())
+}
+
+// GetInformationContext is like GetInformation but uses ctx for the request.
+func (device Device) GetInformationContext(ctx context.Context) (DeviceInformation, error) {
 	// Create SOAP
 	soap := SOAP{
 		Body:     "<tds:GetDeviceInformation/>",
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 	}
 
 	// Send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return DeviceInformation{}, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetDeviceInformationResponse"`
+		DeviceInformation
 	}
-
-	// Parse response to interface
-	deviceInfo, err := response.ValueForPath("Envelope.Body.GetDeviceInformationResponse")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return DeviceInformation{}, err
 	}
 
-	// Parse interface to struct
-	result := DeviceInformation{}
-	if mapInfo, ok := deviceInfo.(map[string]interface{}); ok {
-		result.Manufacturer = interfaceToString(mapInfo["Manufacturer"])
-		result.Model = interfaceToString(mapInfo["Model"])
-		result.FirmwareVersion = interfaceToString(mapInfo["FirmwareVersion"])
-		result.SerialNumber = interfaceToString(mapInfo["SerialNumber"])
-		result.HardwareID = interfaceToString(mapInfo["HardwareId"])
-	}
-
-	return result, nil
+	return response.DeviceInformation, nil
 }
 
 // GetInformation fetch information of ONVIF camera
 func (device Device) GetNetworkInterfaces() ([]NetworkInterface, error) {
+	return device.GetNetworkInterfacesContext(context.Background())
+}
+
+// GetNetworkInterfacesContext is like GetNetworkInterfaces but uses ctx for the request.
+func (device Device) GetNetworkInterfacesContext(ctx context.Context) ([]NetworkInterface, error) {
 	// Create SOAP
 	soap := SOAP{
 		Body:     "<tds:GetNetworkInterfaces/>",
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 	}
 
 	// Send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return nil, err
+	var response struct {
+		XMLName           xml.Name           `xml:"http://www.onvif.org/ver10/device/wsdl GetNetworkInterfacesResponse"`
+		NetworkInterfaces []NetworkInterface `xml:"NetworkInterfaces"`
 	}
-
-	// Parse response to interface
-	networkInterfacesInfos, err := response.ValuesForPath("Envelope.Body.GetNetworkInterfacesResponse.NetworkInterfaces")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return nil, err
 	}
 
-	// Parse interface to struct
-	result := make([]NetworkInterface, 0)
-	for _, networkInterfacesInfo := range networkInterfacesInfos {
-		if mapNetworkInterfacesInfo, ok := networkInterfacesInfo.(map[string]interface{}); ok {
-			glog.Infof("mapNetworkInterfacesInfo %v", mapNetworkInterfacesInfo)
-			networkInterface := NetworkInterface{}
-			networkInterface.Token = interfaceToString(mapNetworkInterfacesInfo["-token"])
-			networkInterface.Enabled = interfaceToBool(mapNetworkInterfacesInfo["Enabled"])
-			if mapIPv4Info, ok := mapNetworkInterfacesInfo["IPv4"].(map[string]interface{}); ok {
-				networkInterface.IPv4.Enabled = interfaceToBool(mapIPv4Info["Enabled"])
-				if mapIPv4Config, ok := mapIPv4Info["Config"].(map[string]interface{}); ok {
-					networkInterface.IPv4.Config.DHCP = interfaceToBool(mapIPv4Config["DHCP"])
-					if mapIPv4FromDHCPConfig, ok := mapIPv4Config["FromDHCP"].(map[string]interface{}); ok {
-						networkInterface.IPv4.Config.FromDHCP.Address = interfaceToString(mapIPv4FromDHCPConfig["Address"])
-						networkInterface.IPv4.Config.FromDHCP.PrefixLength = interfaceToInt(mapIPv4FromDHCPConfig["PrefixLength"])
-					}
-					if mapIPv4ManualConfig, ok := mapIPv4Config["Manual"].(map[string]interface{}); ok {
-						networkInterface.IPv4.Config.Manual.Address = interfaceToString(mapIPv4ManualConfig["Address"])
-						networkInterface.IPv4.Config.Manual.PrefixLength = interfaceToInt(mapIPv4ManualConfig["PrefixLength"])
-					}
-					if mapIPv4LinkLocalConfig, ok := mapIPv4Config["LinkLocal"].(map[string]interface{}); ok {
-						networkInterface.IPv4.Config.LinkLocal.Address = interfaceToString(mapIPv4LinkLocalConfig["Address"])
-						networkInterface.IPv4.Config.LinkLocal.PrefixLength = interfaceToInt(mapIPv4LinkLocalConfig["PrefixLength"])
-					}
-				}
-			}
-			if mapInfo, ok := mapNetworkInterfacesInfo["Info"].(map[string]interface{}); ok {
-				networkInterface.Info.Name = interfaceToString(mapInfo["Name"])
-				networkInterface.Info.MTU = interfaceToInt(mapInfo["MTU"])
-				networkInterface.Info.HwAddress = interfaceToString(mapInfo["HwAddress"])
-			}
-			result = append(result, networkInterface)
-		}
+	result := make([]NetworkInterface, 0, len(response.NetworkInterfaces))
+	for _, networkInterface := range response.NetworkInterfaces {
+		device.logger().Log(LevelDebug, "network interface", "interface", networkInterface)
+		result = append(result, networkInterface)
 	}
 
 	return result, nil
 }
 
 func (device Device) SetNetworkInterfaces(networkInterface NetworkInterface) error {
+	return device.SetNetworkInterfacesContext(context.Background(), networkInterface)
+}
+
+// SetNetworkInterfacesContext is like SetNetworkInterfaces but uses ctx for the request.
+func (device Device) SetNetworkInterfacesContext(ctx context.Context, networkInterface NetworkInterface) error {
 	//create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<SetNetworkInterfaces xmlns="http://www.onvif.org/ver10/device/wsdl">
-					<InterfaceToken>` + networkInterface.Token + `</InterfaceToken>
+					<InterfaceToken>` + xmlEscape(networkInterface.Token) + `</InterfaceToken>
 					<NetworkInterface>
 						<Enabled xmlns="http://www.onvif.org/ver10/schema">` + boolToString(networkInterface.Enabled) + `</Enabled>
 						<MTU xmlns="http://www.onvif.org/ver10/schema">` + intToString(networkInterface.Info.MTU) + `</MTU>
 						<IPv4 xmlns="http://www.onvif.org/ver10/schema">
 							<Enabled>true</Enabled>
 							<Manual>
-								<Address>` + networkInterface.IPv4.Config.Manual.Address + `</Address>
+								<Address>` + xmlEscape(networkInterface.IPv4.Config.Manual.Address) + `</Address>
 								<PrefixLength>` + intToString(networkInterface.IPv4.Config.Manual.PrefixLength) + `</PrefixLength>
 							</Manual>
 							<DHCP>` + boolToString(networkInterface.IPv4.Config.DHCP) + `</DHCP>
@@ -126,22 +103,21 @@ func (device Device) SetNetworkInterfaces(networkInterface NetworkInterface) err
 					</NetworkInterface>
  			  </SetNetworkInterfaces>`,
 	}
-	// send request
-	response, err := soap.SendRequest(device.XAddr)
 
-	if err != nil {
-		return err
-	}
-
-	_, err = response.ValueForPath("Envelope.Body.SetNetworkInterfacesResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetNetworkInterfacesResponse"`
 	}
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 // GetCapabilities fetch info of ONVIF camera's capabilities
 func (device Device) GetCapabilities() (DeviceCapabilities, error) {
+	return device.GetCapabilitiesContext(context.Background())
+}
+
+// GetCapabilitiesContext is like GetCapabilities but uses ctx for the request.
+func (device Device) GetCapabilitiesContext(ctx context.Context) (DeviceCapabilities, error) {
 	// Create SOAP
 	soap := SOAP{
 		XMLNs: deviceXMLNs,
@@ -150,116 +126,80 @@ func (device Device) GetCapabilities() (DeviceCapabilities, error) {
 		</tds:GetCapabilities>`,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 	}
 
 	// Send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return DeviceCapabilities{}, err
+	var response struct {
+		XMLName      xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetCapabilitiesResponse"`
+		Capabilities struct {
+			Device struct {
+				Network NetworkCapabilities
+			}
+			Events *xmlElement
+			Media  struct {
+				XAddr                 string
+				StreamingCapabilities xmlElement
+			}
+			PTZ       PTZCapabilities
+			Extension struct {
+				Recording ExtensionCapabilities
+				Search    ExtensionCapabilities
+				Replay    ExtensionCapabilities
+			}
+		}
 	}
-
-	// Get network capabilities
-	envelopeBodyPath := "Envelope.Body.GetCapabilitiesResponse.Capabilities"
-	ifaceNetCap, err := response.ValueForPath(envelopeBodyPath + ".Device.Network")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return DeviceCapabilities{}, err
 	}
-
-	netCap := NetworkCapabilities{}
-	if mapNetCap, ok := ifaceNetCap.(map[string]interface{}); ok {
-		netCap.DynDNS = interfaceToBool(mapNetCap["DynDNS"])
-		netCap.IPFilter = interfaceToBool(mapNetCap["IPFilter"])
-		netCap.IPVersion6 = interfaceToBool(mapNetCap["IPVersion6"])
-		netCap.ZeroConfig = interfaceToBool(mapNetCap["ZeroConfiguration"])
-	}
+	capabilities := response.Capabilities
 
 	// Get events capabilities
-	ifaceEventsCap, err := response.ValueForPath(envelopeBodyPath + ".Events")
 	eventsCap := EventsCapabilities{}
-	if err == nil {
+	if capabilities.Events != nil {
 		eventsCap.Events = map[string]bool{}
-		if mapEventsCap, ok := ifaceEventsCap.(map[string]interface{}); ok {
-			for key, value := range mapEventsCap {
-				if strings.ToLower(key) == "xaddr" {
-					eventsCap.XAddr = interfaceToString(value)
-					continue
-				}
-
-				key = strings.Replace(key, "WS", "", 1)
-				eventsCap.Events[key] = interfaceToBool(value)
+		flags := xmlElement{}
+		for _, child := range capabilities.Events.Children {
+			if strings.ToLower(child.XMLName.Local) == "xaddr" {
+				eventsCap.XAddr = strings.TrimSpace(child.Text)
+				continue
 			}
+			flags.Children = append(flags.Children, child)
 		}
-	}
 
-	// Get streaming capabilities
-	ifaceStreamingCap, err := response.ValueForPath(envelopeBodyPath + ".Media.StreamingCapabilities")
-	if err != nil {
-		return DeviceCapabilities{}, err
-	}
-
-	streamingCap := make(map[string]bool)
-	if mapStreamingCap, ok := ifaceStreamingCap.(map[string]interface{}); ok {
-		for key, value := range mapStreamingCap {
-			key = strings.Replace(key, "_", " ", -1)
-			streamingCap[key] = interfaceToBool(value)
+		events, err := flags.bools()
+		if err != nil {
+			return DeviceCapabilities{}, err
+		}
+		for key, value := range events {
+			key = strings.Replace(key, "WS", "", 1)
+			eventsCap.Events[key] = value
 		}
 	}
 
-	// Get media capabilities
-	ifaceMediaCap, err := response.ValueForPath(envelopeBodyPath + ".Media")
+	// Get streaming capabilities
+	streaming, err := capabilities.Media.StreamingCapabilities.bools()
 	if err != nil {
 		return DeviceCapabilities{}, err
 	}
 
-	mediaCap := MediaCapabilities{}
-	if mapMediaCap, ok := ifaceMediaCap.(map[string]interface{}); ok {
-		mediaCap.XAddr = interfaceToString(mapMediaCap["XAddr"])
-	}
-
-	// Get Ptz capabilities
-	ifacePtzCap, err := response.ValueForPath(envelopeBodyPath + ".PTZ")
-	ptzCap := PTZCapabilities{}
-	if err == nil {
-		if mapPtzCap, ok := ifacePtzCap.(map[string]interface{}); ok {
-			ptzCap.XAddr = interfaceToString(mapPtzCap["XAddr"])
-		}
-	}
-
-	// Get Extension capabilities
-	recordingCap := ExtensionCapabilities{}
-	searchRecodingCap := ExtensionCapabilities{}
-	relayCap := ExtensionCapabilities{}
-	ifaceExtensionCap, err := response.ValueForPath(envelopeBodyPath + ".Extension")
-	if err == nil {
-		if mapExtensionCap, ok := ifaceExtensionCap.(map[string]interface{}); ok {
-			if mapRecordingCapIface, ok := mapExtensionCap["Recording"]; ok {
-				if mapRecordingCap, ok := mapRecordingCapIface.(map[string]interface{}); ok {
-					recordingCap.XAddr = interfaceToString(mapRecordingCap["XAddr"])
-				}
-			}
-			if mapSearchRecordingCapIface, ok := mapExtensionCap["Search"]; ok {
-				if mapSearchRecordingCap, ok := mapSearchRecordingCapIface.(map[string]interface{}); ok {
-					searchRecodingCap.XAddr = interfaceToString(mapSearchRecordingCap["XAddr"])
-				}
-			}
-			if mapRelayCapIface, ok := mapExtensionCap["Replay"]; ok {
-				if mapRelayCap, ok := mapRelayCapIface.(map[string]interface{}); ok {
-					relayCap.XAddr = interfaceToString(mapRelayCap["XAddr"])
-				}
-			}
-		}
+	streamingCap := make(map[string]bool)
+	for key, value := range streaming {
+		key = strings.Replace(key, "_", " ", -1)
+		streamingCap[key] = value
 	}
 
 	// Create final result
 	deviceCapabilities := DeviceCapabilities{
-		Network:         netCap,
-		Media:           mediaCap,
-		Ptz:             ptzCap,
+		Network:         capabilities.Device.Network,
+		Media:           MediaCapabilities{XAddr: capabilities.Media.XAddr},
+		Ptz:             capabilities.PTZ,
 		EventsCap:       eventsCap,
 		Streaming:       streamingCap,
-		Recording:       recordingCap,
-		Replay:          relayCap,
-		SearchRecording: searchRecodingCap,
+		Recording:       capabilities.Extension.Recording,
+		Replay:          capabilities.Extension.Replay,
+		SearchRecording: capabilities.Extension.Search,
 	}
 
 	return deviceCapabilities, nil
@@ -267,54 +207,63 @@ func (device Device) GetCapabilities() (DeviceCapabilities, error) {
 
 // GetDiscoveryMode fetch network discovery mode of an ONVIF camera
 func (device Device) GetDiscoveryMode() (string, error) {
+	return device.GetDiscoveryModeContext(context.Background())
+}
+
+// GetDiscoveryModeContext is like GetDiscoveryMode but uses ctx for the request.
+func (device Device) GetDiscoveryModeContext(ctx context.Context) (string, error) {
 	// Create SOAP
 	soap := SOAP{
 		Body:     "<tds:GetDiscoveryMode/>",
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 	}
 
 	// Send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
+	var response struct {
+		XMLName       xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetDiscoveryModeResponse"`
+		DiscoveryMode string
+	}
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return "", err
 	}
 
-	// Parse response
-	discoveryMode, _ := response.ValueForPathString("Envelope.Body.GetDiscoveryModeResponse.DiscoveryMode")
-	return discoveryMode, nil
+	return response.DiscoveryMode, nil
 }
 
 // GetScopes fetch scopes of an ONVIF camera
 func (device Device) GetScopes() ([]string, error) {
+	return device.GetScopesContext(context.Background())
+}
+
+// GetScopesContext is like GetScopes but uses ctx for the request.
+func (device Device) GetScopesContext(ctx context.Context) ([]string, error) {
 	// Create SOAP
 	soap := SOAP{
 		Body:     "<tds:GetScopes/>",
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 	}
 
 	// Send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return nil, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetScopesResponse"`
+		Scopes  []Scope
 	}
-
-	// Parse response to interface
-	ifaceScopes, err := response.ValuesForPath("Envelope.Body.GetScopesResponse.Scopes")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return nil, err
 	}
 
-	// Convert interface to array of scope
+	// Convert to array of scope
 	scopes := []string{}
-	for _, ifaceScope := range ifaceScopes {
-		if mapScope, ok := ifaceScope.(map[string]interface{}); ok {
-			scope := interfaceToString(mapScope["ScopeItem"])
-			scopes = append(scopes, scope)
-		}
+	for _, scope := range response.Scopes {
+		scopes = append(scopes, scope.ScopeItem)
 	}
 
 	return scopes, nil
@@ -322,96 +271,90 @@ func (device Device) GetScopes() ([]string, error) {
 
 // GetHostname fetch hostname of an ONVIF camera
 func (device Device) GetHostname() (HostnameInformation, error) {
+	return device.GetHostnameContext(context.Background())
+}
+
+// GetHostnameContext is like GetHostname but uses ctx for the request.
+func (device Device) GetHostnameContext(ctx context.Context) (HostnameInformation, error) {
 	// Create SOAP
 	soap := SOAP{
 		Body:     "<tds:GetHostname/>",
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 	}
 
 	// Send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return HostnameInformation{}, err
+	var response struct {
+		XMLName             xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetHostnameResponse"`
+		HostnameInformation HostnameInformation
 	}
-
-	// Parse response to interface
-	ifaceHostInfo, err := response.ValueForPath("Envelope.Body.GetHostnameResponse.HostnameInformation")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return HostnameInformation{}, err
 	}
 
-	// Parse interface to struct
-	hostnameInfo := HostnameInformation{}
-	if mapHostInfo, ok := ifaceHostInfo.(map[string]interface{}); ok {
-		hostnameInfo.Name = interfaceToString(mapHostInfo["Name"])
-		hostnameInfo.FromDHCP = interfaceToBool(mapHostInfo["FromDHCP"])
-	}
-
-	return hostnameInfo, nil
+	return response.HostnameInformation, nil
 }
 
 func (device Device) GetSystemDateAndTime() (SystemDateAndTime, error) {
+	return device.GetSystemDateAndTimeContext(context.Background())
+}
+
+// GetSystemDateAndTimeContext is like GetSystemDateAndTime but uses ctx for the request.
+func (device Device) GetSystemDateAndTimeContext(ctx context.Context) (SystemDateAndTime, error) {
 	// Create SOAP
 	soap := SOAP{
-		XMLNs: deviceXMLNs,
-		Body:  `<GetSystemDateAndTime xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
+		XMLNs:  deviceXMLNs,
+		Body:   `<GetSystemDateAndTime xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
+		Client: device.Client,
 	}
 
 	systemDT := SystemDateAndTime{}
 
 	// send SOAP request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return systemDT, err
+	var response struct {
+		XMLName           xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetSystemDateAndTimeResponse"`
+		SystemDateAndTime struct {
+			DateTimeType    string
+			DaylightSavings bool
+			TimeZone        TimeZone
+			UTCDateTime     struct {
+				Time Time
+				Date Date
+			}
+		}
 	}
-
-	// Parse response to interface
-	ifaceSystemDateTime, err := response.ValueForPath("Envelope.Body.GetSystemDateAndTimeResponse.SystemDateAndTime")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return systemDT, err
 	}
 
-	// Parse response to struct
-	if mapSystemDateTime, ok := ifaceSystemDateTime.(map[string]interface{}); ok {
-		systemDT.DateTimeType = interfaceToString(mapSystemDateTime["DateTimeType"])
-		systemDT.DaylightSavings = interfaceToBool(mapSystemDateTime["DaylightSavings"])
-
-		if ifaceTZ, ok := mapSystemDateTime["TimeZone"].(map[string]interface{}); ok {
-			systemDT.TZ = interfaceToString(ifaceTZ["TZ"])
-		}
-
-		if ifaceUCTDT, ok := mapSystemDateTime["UTCDateTime"].(map[string]interface{}); ok {
-			time := ifaceUCTDT["Time"]
-			if mapTime, ok := time.(map[string]interface{}); ok {
-				systemDT.Hour = interfaceToInt(mapTime["Hour"])
-				systemDT.Minute = interfaceToInt(mapTime["Minute"])
-				systemDT.Second = interfaceToInt(mapTime["Second"])
-			}
-
-			date := ifaceUCTDT["Date"]
-
-			if mapDate, ok := date.(map[string]interface{}); ok {
-				systemDT.Day = interfaceToInt(mapDate["Day"])
-				systemDT.Month = interfaceToInt(mapDate["Month"])
-				systemDT.Year = interfaceToInt(mapDate["Year"])
-			}
-		}
-	}
+	// Flatten response into struct
+	dateTime := response.SystemDateAndTime
+	systemDT.DateTimeType = dateTime.DateTimeType
+	systemDT.DaylightSavings = dateTime.DaylightSavings
+	systemDT.TimeZone = dateTime.TimeZone
+	systemDT.Time = dateTime.UTCDateTime.Time
+	systemDT.Date = dateTime.UTCDateTime.Date
 
 	return systemDT, nil
 }
 
 func (device Device) SetSystemDateAndTime(systemDT SystemDateAndTime) error {
+	return device.SetSystemDateAndTimeContext(context.Background(), systemDT)
+}
+
+// SetSystemDateAndTimeContext is like SetSystemDateAndTime but uses ctx for the request.
+func (device Device) SetSystemDateAndTimeContext(ctx context.Context, systemDT SystemDateAndTime) error {
 	// create Body request
 	var body string
 	if systemDT.DateTimeType == "Manual" { // Manual mode
 		body = `<SetSystemDateAndTime xmlns="http://www.onvif.org/ver10/device/wsdl">
-					<DateTimeType>` + systemDT.DateTimeType + `</DateTimeType>
+					<DateTimeType>` + xmlEscape(systemDT.DateTimeType) + `</DateTimeType>
 					<DaylightSavings>` + boolToString(systemDT.DaylightSavings) + `</DaylightSavings>
 					<TimeZone>
-						<TZ xmlns="http://www.onvif.org/ver10/schema">` + systemDT.TZ + `</TZ>
+						<TZ xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(systemDT.TZ) + `</TZ>
 					</TimeZone>
 					<UTCDateTime>
 						<Time xmlns="http://www.onvif.org/ver10/schema">
@@ -428,11 +371,11 @@ func (device Device) SetSystemDateAndTime(systemDT SystemDateAndTime) error {
 				</SetSystemDateAndTime>`
 	} else { // NTP mode
 		body = `<SetSystemDateAndTime xmlns="http://www.onvif.org/ver10/device/wsdl">
-					<DateTimeType>` + systemDT.DateTimeType + `</DateTimeType>
+					<DateTimeType>` + xmlEscape(systemDT.DateTimeType) + `</DateTimeType>
 					<DaylightSavings>` + boolToString(systemDT.DaylightSavings) + `</DaylightSavings>`
 
 		if systemDT.TZ != "" {
-			body += `<TimeZone><TZ xmlns="http://www.onvif.org/ver10/schema">` + systemDT.TZ + `</TZ></TimeZone>`
+			body += `<TimeZone><TZ xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(systemDT.TZ) + `</TZ></TimeZone>`
 		}
 
 		body += `</SetSystemDateAndTime>`
@@ -442,360 +385,295 @@ func (device Device) SetSystemDateAndTime(systemDT SystemDateAndTime) error {
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     body,
 	}
 
-	// send soap request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
-	}
-
-	_, err = response.ValueForPath("Envelope.Body.SetSystemDateAndTimeResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetSystemDateAndTimeResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) GetNTP() (NTPInformation, error) {
+	return device.GetNTPContext(context.Background())
+}
+
+// GetNTPContext is like GetNTP but uses ctx for the request.
+func (device Device) GetNTPContext(ctx context.Context) (NTPInformation, error) {
 	//create SOAP
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetNTP xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
 	ntpInformation := NTPInformation{}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-
-	if err != nil {
-		return ntpInformation, err
+	var response struct {
+		XMLName        xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetNTPResponse"`
+		NTPInformation struct {
+			FromDHCP    bool
+			NTPFromDHCP []NetworkHost
+			NTPManual   []NetworkHost
+		}
 	}
-
-	// parse response into interface
-	ifaceNTPInformation, err := response.ValueForPath("Envelope.Body.GetNTPResponse.NTPInformation")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return ntpInformation, err
 	}
 
-	// parse interface to struct
-	if mapNTPInformation, ok := ifaceNTPInformation.(map[string]interface{}); ok {
-		ntpInformation.FromDHCP = interfaceToBool(mapNTPInformation["FromDHCP"])
-		ntpInformation.NTPNetworkHost = make([]NetworkHost, 0)
-		if ntpInformation.FromDHCP {
-			if ifaceManuals, ok := mapNTPInformation["NTPFromDHCP"].([]interface{}); ok {
-				for _, ifaceManual := range ifaceManuals {
-					if mapNTPManual, ok := ifaceManual.(map[string]interface{}); ok {
-						NTPNetworkHost := NetworkHost{}
-
-						NTPNetworkHost.Type = interfaceToString(mapNTPManual["Type"])
-						NTPNetworkHost.IPv4Address = interfaceToString(mapNTPManual["IPv4Address"])
-						NTPNetworkHost.DNSname = interfaceToString(mapNTPManual["DNSname"])
-
-						ntpInformation.NTPNetworkHost = append(ntpInformation.NTPNetworkHost, NTPNetworkHost)
-					}
-				}
-			} else {
-				if mapNTPManual, ok := mapNTPInformation["NTPFromDHCP"].(map[string]interface{}); ok {
-					NTPNetworkHost := NetworkHost{}
-
-					NTPNetworkHost.Type = interfaceToString(mapNTPManual["Type"])
-					NTPNetworkHost.IPv4Address = interfaceToString(mapNTPManual["IPv4Address"])
-					NTPNetworkHost.DNSname = interfaceToString(mapNTPManual["DNSname"])
-
-					ntpInformation.NTPNetworkHost = append(ntpInformation.NTPNetworkHost, NTPNetworkHost)
-				}
-			}
-
-		} else {
-			if ifaceManuals, ok := mapNTPInformation["NTPManual"].([]interface{}); ok {
-				for _, ifaceManual := range ifaceManuals {
-					if mapNTPManual, ok := ifaceManual.(map[string]interface{}); ok {
-						NTPNetworkHost := NetworkHost{}
-
-						NTPNetworkHost.Type = interfaceToString(mapNTPManual["Type"])
-						NTPNetworkHost.IPv4Address = interfaceToString(mapNTPManual["IPv4Address"])
-						NTPNetworkHost.DNSname = interfaceToString(mapNTPManual["DNSname"])
-
-						ntpInformation.NTPNetworkHost = append(ntpInformation.NTPNetworkHost, NTPNetworkHost)
-					}
-				}
-			} else {
-				if mapNTPManual, ok := mapNTPInformation["NTPManual"].(map[string]interface{}); ok {
-					NTPNetworkHost := NetworkHost{}
-
-					NTPNetworkHost.Type = interfaceToString(mapNTPManual["Type"])
-					NTPNetworkHost.IPv4Address = interfaceToString(mapNTPManual["IPv4Address"])
-					NTPNetworkHost.DNSname = interfaceToString(mapNTPManual["DNSname"])
-
-					ntpInformation.NTPNetworkHost = append(ntpInformation.NTPNetworkHost, NTPNetworkHost)
-				}
-			}
-		}
+	information := response.NTPInformation
+	ntpInformation.FromDHCP = information.FromDHCP
+	ntpInformation.NTPNetworkHost = make([]NetworkHost, 0)
+	if information.FromDHCP {
+		ntpInformation.NTPNetworkHost = append(ntpInformation.NTPNetworkHost, information.NTPFromDHCP...)
+	} else {
+		ntpInformation.NTPNetworkHost = append(ntpInformation.NTPNetworkHost, information.NTPManual...)
 	}
 
 	return ntpInformation, nil
 }
 
 func (device Device) SetNTP(ntpInformation NTPInformation) error {
+	return device.SetNTPContext(context.Background(), ntpInformation)
+}
+
+// SetNTPContext is like SetNTP but uses ctx for the request.
+func (device Device) SetNTPContext(ctx context.Context, ntpInformation NTPInformation) error {
 	// create soap
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<SetNTP xmlns="http://www.onvif.org/ver10/device/wsdl">
 					<FromDHCP>` + boolToString(ntpInformation.FromDHCP) + `</FromDHCP>
 					<NTPManual>
-						<Type xmlns="http://www.onvif.org/ver10/schema">` + ntpInformation.NTPNetworkHost[0].Type + `</Type>
-						<IPv4Address xmlns="http://www.onvif.org/ver10/schema">` + ntpInformation.NTPNetworkHost[0].IPv4Address + `</IPv4Address>
-						<DNSname xmlns="http://www.onvif.org/ver10/schema">` + ntpInformation.NTPNetworkHost[0].DNSname + `</DNSname>
+						<Type xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(ntpInformation.NTPNetworkHost[0].Type) + `</Type>
+						<IPv4Address xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(ntpInformation.NTPNetworkHost[0].IPv4Address) + `</IPv4Address>
+						<DNSname xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(ntpInformation.NTPNetworkHost[0].DNSname) + `</DNSname>
 					</NTPManual>
 				</SetNTP>`,
 	}
 
-	// send soap request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
-	}
-
-	_, err = response.ValueForPath("Envelope.Body.SetNTPResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetNTPResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) SystemReboot() (string, error) {
+	return device.SystemRebootContext(context.Background())
+}
+
+// SystemRebootContext is like SystemReboot but uses ctx for the request.
+func (device Device) SystemRebootContext(ctx context.Context) (string, error) {
 	// create SOAP
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<SystemReboot xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
-	var message string
-
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-
-	if err != nil {
-		return message, err
-	}
-
-	// parse response into interface
-	ifaceMessage, err := response.ValueForPath("Envelope.Body.SystemRebootResponse")
-	if err != nil {
-		return message, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SystemRebootResponse"`
+		Message string
 	}
-
-	// parse message reboot
-	if mapMessage, ok := ifaceMessage.(map[string]interface{}); ok {
-		message = interfaceToString(mapMessage["Message"])
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return "", err
 	}
 
-	return message, nil
+	return response.Message, nil
 }
 
 func (device Device) GetDNS() (DNSInformation, error) {
+	return device.GetDNSContext(context.Background())
+}
+
+// GetDNSContext is like GetDNS but uses ctx for the request.
+func (device Device) GetDNSContext(ctx context.Context) (DNSInformation, error) {
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetDNS xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
 	dnsInformation := DNSInformation{}
 
 	// send soap request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return dnsInformation, err
+	var response struct {
+		XMLName        xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetDNSResponse"`
+		DNSInformation struct {
+			FromDHCP     bool
+			SearchDomain string
+			DNSFromDHCP  []IPAddress
+			DNSManual    []IPAddress
+		}
 	}
-
-	// parse response to interface
-	ifaceDNSInformation, err := response.ValueForPath("Envelope.Body.GetDNSResponse.DNSInformation")
-
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return dnsInformation, err
 	}
 
-	// parse interface to map
-	if mapDNSInformation, ok := ifaceDNSInformation.(map[string]interface{}); ok {
-		dnsInformation.FromDHCP = interfaceToBool(mapDNSInformation["FromDHCP"])
-		dnsInformation.SearchDomain = interfaceToString(mapDNSInformation["SearchDomain"])
-
-		if dnsInformation.FromDHCP {
-			if mapListDNSFromDHCP, ok := mapDNSInformation["DNSFromDHCP"].([]interface{}); ok {
-				for _, mapDNSFromDHCPIf := range mapListDNSFromDHCP {
-					DNSAddress := IPAddress{}
-					if mapDNSFromDHCP, ok := mapDNSFromDHCPIf.(map[string]interface{}); ok {
-						DNSAddress.Type = interfaceToString(mapDNSFromDHCP["Type"])
-						DNSAddress.IPv4Address = interfaceToString(mapDNSFromDHCP["IPv4Address"])
-					}
-					dnsInformation.DNSAddress = append(dnsInformation.DNSAddress, DNSAddress)
-				}
-			}
-		} else {
-			if mapListDNSManual, ok := mapDNSInformation["DNSManual"].([]interface{}); ok {
-				for _, mapDNSManualIf := range mapListDNSManual {
-					DNSAddress := IPAddress{}
-					if mapDNSManual, ok := mapDNSManualIf.(map[string]interface{}); ok {
-						DNSAddress.Type = interfaceToString(mapDNSManual["Type"])
-						DNSAddress.IPv4Address = interfaceToString(mapDNSManual["IPv4Address"])
-					}
-					dnsInformation.DNSAddress = append(dnsInformation.DNSAddress, DNSAddress)
-				}
-			}
-		}
+	information := response.DNSInformation
+	dnsInformation.FromDHCP = information.FromDHCP
+	dnsInformation.SearchDomain = information.SearchDomain
+	if information.FromDHCP {
+		dnsInformation.DNSAddress = information.DNSFromDHCP
+	} else {
+		dnsInformation.DNSAddress = information.DNSManual
 	}
 
 	return dnsInformation, nil
 }
 
 func (device Device) SetDNS(dnsInformation DNSInformation) error {
+	return device.SetDNSContext(context.Background(), dnsInformation)
+}
+
+// SetDNSContext is like SetDNS but uses ctx for the request.
+func (device Device) SetDNSContext(ctx context.Context, dnsInformation DNSInformation) error {
 	// create soap
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<SetDNS xmlns="http://www.onvif.org/ver10/device/wsdl">
 				<FromDHCP>` + boolToString(dnsInformation.FromDHCP) + `</FromDHCP>
-				<SearchDomain>` + dnsInformation.SearchDomain + `</SearchDomain>
+				<SearchDomain>` + xmlEscape(dnsInformation.SearchDomain) + `</SearchDomain>
 				<DNSManual>
-					<Type xmlns="http://www.onvif.org/ver10/schema">` + dnsInformation.DNSAddress[0].Type + `</Type>
-					<IPv4Address xmlns="http://www.onvif.org/ver10/schema">` + dnsInformation.DNSAddress[0].IPv4Address + `</IPv4Address>
+					<Type xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(dnsInformation.DNSAddress[0].Type) + `</Type>
+					<IPv4Address xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(dnsInformation.DNSAddress[0].IPv4Address) + `</IPv4Address>
 				</DNSManual>
 			  </SetDNS>`,
 	}
 
-	// send soap request
-	response, err := soap.SendRequest(device.XAddr)
-
-	if err != nil {
-		return err
-	}
-
-	_, err = response.ValueForPath("Envelope.Body.SetDNSResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetDNSResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) GetDynamicDNS() (DynamicDNSInformation, error) {
+	return device.GetDynamicDNSContext(context.Background())
+}
+
+// GetDynamicDNSContext is like GetDynamicDNS but uses ctx for the request.
+func (device Device) GetDynamicDNSContext(ctx context.Context) (DynamicDNSInformation, error) {
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetDynamicDNS xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
-	result := DynamicDNSInformation{}
 
 	// send resquest
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName               xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetDynamicDNSResponse"`
+		DynamicDNSInformation DynamicDNSInformation
 	}
-
-	// parse response
-	ifaceDynamicDNS, err := response.ValueForPath("Envelope.Body.GetDynamicDNSResponse")
-	if err != nil {
-		return result, err
-	}
-
-	// parse interface
-	if mapDynamicDNS, ok := ifaceDynamicDNS.(map[string]interface{}); ok {
-		if mapDynamicDNSInformation, ok := mapDynamicDNS["DynamicDNSInformation"].(map[string]interface{}); ok {
-			result.Type = interfaceToString(mapDynamicDNSInformation["Type"])
-			result.Name = interfaceToString(mapDynamicDNSInformation["Name"])
-			result.TTL = interfaceToString(mapDynamicDNSInformation["TTL"])
-		}
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return DynamicDNSInformation{}, err
 	}
 
-	return result, nil
+	return response.DynamicDNSInformation, nil
 }
 
 func (device Device) SetHostName(nameToken string) error {
+	return device.SetHostNameContext(context.Background(), nameToken)
+}
+
+// SetHostNameContext is like SetHostName but uses ctx for the request.
+func (device Device) SetHostNameContext(ctx context.Context, nameToken string) error {
 	// create soap
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<SetHostname xmlns="http://www.onvif.org/ver10/device/wsdl">
-				<Name xmlns="http://www.onvif.org/ver10/schema">` + nameToken + `</Name>
+				<Name xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(nameToken) + `</Name>
 			   </SetHostname>`,
 	}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetHostnameResponse"`
 	}
-
-	_, err = response.ValueForPath("Envelope.Body.SetHostnameResponse")
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) GetNetworkProtocols() ([]NetworkProtocol, error) {
+	return device.GetNetworkProtocolsContext(context.Background())
+}
+
+// GetNetworkProtocolsContext is like GetNetworkProtocols but uses ctx for the request.
+func (device Device) GetNetworkProtocolsContext(ctx context.Context) ([]NetworkProtocol, error) {
 	// create soap
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
-		Body:     `<GetNetworkProtocols xmlns:="http://www.onvif.org/ver10/device/wsdl"/>`,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
+		Body:     `<GetNetworkProtocols xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
 	result := []NetworkProtocol{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName          xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetNetworkProtocolsResponse"`
+		NetworkProtocols []NetworkProtocol
 	}
-
-	// parse response to interface
-	ifaceNetworkProtocols, err := response.ValuesForPath("Envelope.Body.GetNetworkProtocolsResponse.NetworkProtocols")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
 
-	// parse iface
-	for _, ifaceNetworkProtocol := range ifaceNetworkProtocols {
-		if mapNetworkProtocol, ok := ifaceNetworkProtocol.(map[string]interface{}); ok {
-			networkProtocol := NetworkProtocol{}
-
-			networkProtocol.Name = interfaceToString(mapNetworkProtocol["Name"])
-			networkProtocol.Enabled = interfaceToBool(mapNetworkProtocol["Enabled"])
-			networkProtocol.Port = interfaceToInt(mapNetworkProtocol["Port"])
-
-			result = append(result, networkProtocol)
-		}
-	}
-
-	return result, nil
+	return append(result, response.NetworkProtocols...), nil
 }
 
 func (device Device) SetNetworkProtocols(protocols []NetworkProtocol) error {
+	return device.SetNetworkProtocolsContext(context.Background(), protocols)
+}
+
+// SetNetworkProtocolsContext is like SetNetworkProtocols but uses ctx for the request.
+func (device Device) SetNetworkProtocolsContext(ctx context.Context, protocols []NetworkProtocol) error {
 	// create body for array protocols
-	var protocolsBody string = ``
+	type networkProtocol struct {
+		Name    string `xml:"http://www.onvif.org/ver10/schema Name"`
+		Enabled bool   `xml:"http://www.onvif.org/ver10/schema Enabled"`
+		Port    int    `xml:"http://www.onvif.org/ver10/schema Port"`
+	}
+	request := struct {
+		XMLName          xml.Name          `xml:"http://www.onvif.org/ver10/device/wsdl SetNetworkProtocols"`
+		NetworkProtocols []networkProtocol `xml:"NetworkProtocols"`
+	}{}
 	for _, protocol := range protocols {
-		protocolsBody += `<NetworkProtocols>
-							<Name xmlns="http://www.onvif.org/ver10/schema">` + protocol.Name + `</Name>
-							<Enabled xmlns="http://www.onvif.org/ver10/schema">` + boolToString(protocol.Enabled) + `</Enable>
-							<Port xmlns="http://www.onvif.org/ver10/schema">` + intToString(protocol.Port) + `</Port>
-						  </NetworkProtocols>`
+		request.NetworkProtocols = append(request.NetworkProtocols, networkProtocol(protocol))
+	}
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
 	}
 
 	// create soap
@@ -803,27 +681,28 @@ func (device Device) SetNetworkProtocols(protocols []NetworkProtocol) error {
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
-		Body:     `<SetNetworkProtocols xmlns="http://www.onvif.org/ver10/device/wsdl">` + protocolsBody + `</SetNetworkProtocols>`,
-	}
-	// send soap request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
+		Body:     string(body),
 	}
 
-	_, err = response.ValueForPath("Envelope.Body.SetNetworkProtocolsResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetNetworkProtocolsResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) SetScopes(listScopes []string) error {
+	return device.SetScopesContext(context.Background(), listScopes)
+}
+
+// SetScopesContext is like SetScopes but uses ctx for the request.
+func (device Device) SetScopesContext(ctx context.Context, listScopes []string) error {
 	// create scopes body
 	var scopesBody string
 	for _, scope := range listScopes {
-		scopesBody += `<Scopes>` + scope + `</Scopes>`
+		scopesBody += `<Scopes>` + xmlEscape(scope) + `</Scopes>`
 	}
 
 	// create soap
@@ -831,28 +710,28 @@ func (device Device) SetScopes(listScopes []string) error {
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<SetScopes xmlns="http://www.onvif.org/ver10/device/wsdl">` + scopesBody + `</SetScopes>`,
 	}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
-	}
-
-	_, err = response.ValueForPath("Envelope.Body.SetScopesResponse")
-	if err != nil {
-		return err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetScopesResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) AddScopes(listScopes []string) error {
+	return device.AddScopesContext(context.Background(), listScopes)
+}
+
+// AddScopesContext is like AddScopes but uses ctx for the request.
+func (device Device) AddScopesContext(ctx context.Context, listScopes []string) error {
 	// create scopes body
 	var scopesBody string
 	for _, scope := range listScopes {
-		scopesBody += `<ScopeItem>` + scope + `</ScopeItem>`
+		scopesBody += `<ScopeItem>` + xmlEscape(scope) + `</ScopeItem>`
 	}
 
 	// create soap
@@ -860,202 +739,198 @@ func (device Device) AddScopes(listScopes []string) error {
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<AddScopes xmlns="http://www.onvif.org/ver10/device/wsdl">` + scopesBody + `</AddScopes>`,
 	}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl AddScopesResponse"`
 	}
-
-	_, err = response.ValueForPath("Envelope.Body.AddScopesResponse")
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) RemoveScopes(listScopes []string) ([]string, error) {
+	return device.RemoveScopesContext(context.Background(), listScopes)
+}
+
+// RemoveScopesContext is like RemoveScopes but uses ctx for the request.
+func (device Device) RemoveScopesContext(ctx context.Context, listScopes []string) ([]string, error) {
 	// create scopes body
 	var scopesBody string
 	for _, scope := range listScopes {
-		scopesBody += `<ScopeItem>` + scope + `</ScopeItem>`
+		scopesBody += `<ScopeItem>` + xmlEscape(scope) + `</ScopeItem>`
 	}
 	// create soap
 	soap := SOAP{
 		XMLNs:    deviceXMLNs,
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<RemoveScopes xmlns="http://www.onvif.org/ver10/device/wsdl">` + scopesBody + `</RemoveScopes>`,
 	}
 
-	var result []string
 	// send request
-	respone, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName   xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl RemoveScopesResponse"`
+		ScopeItem []string
 	}
-
-	// parse response
-	ifaceScopeItems, err := respone.ValuesForPath("Envelope.Body.RemoveScopesResponse.ScopeItem")
-	for _, scopeItem := range ifaceScopeItems {
-		result = append(result, interfaceToString(scopeItem))
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return response.ScopeItem, nil
 }
 
 func (device Device) GetNetworkDefaultGateway() (NetworkGateway, error) {
+	return device.GetNetworkDefaultGatewayContext(context.Background())
+}
+
+// GetNetworkDefaultGatewayContext is like GetNetworkDefaultGateway but uses ctx for the request.
+func (device Device) GetNetworkDefaultGatewayContext(ctx context.Context) (NetworkGateway, error) {
 	//create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetNetworkDefaultGateway xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
-	result := NetworkGateway{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName        xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetNetworkDefaultGatewayResponse"`
+		NetworkGateway NetworkGateway
 	}
-
-	// parse response
-	ifaceNetworkGateway, err := response.ValueForPath("Envelope.Body.GetNetworkDefaultGatewayResponse.NetworkGateway")
-	if err != nil {
-		return result, err
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return NetworkGateway{}, err
 	}
 
-	if mapNetworkGateway, ok := ifaceNetworkGateway.(map[string]interface{}); ok {
-		result.IPv4Address = interfaceToString(mapNetworkGateway["IPv4Address"])
-	}
-
-	return result, nil
+	return response.NetworkGateway, nil
 }
 
 func (device Device) SetNetworkDefaultGateway(defaultGateway NetworkGateway) error {
+	return device.SetNetworkDefaultGatewayContext(context.Background(), defaultGateway)
+}
+
+// SetNetworkDefaultGatewayContext is like SetNetworkDefaultGateway but uses ctx for the request.
+func (device Device) SetNetworkDefaultGatewayContext(ctx context.Context, defaultGateway NetworkGateway) error {
 	//create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<SetNetworkDefaultGateway xmlns="http://www.onvif.org/ver10/device/wsdl">
-					<IPv4Address>` + defaultGateway.IPv4Address + `</IPv4Address>
+					<IPv4Address>` + xmlEscape(defaultGateway.IPv4Address) + `</IPv4Address>
  			  </SetNetworkDefaultGateway>`,
 	}
-	// send request
-	response, err := soap.SendRequest(device.XAddr)
-
-	if err != nil {
-		return err
-	}
 
-	_, err = response.ValueForPath("Envelope.Body.SetNetworkDefaultGatewayResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetNetworkDefaultGatewayResponse"`
 	}
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) GetUsers() ([]User, error) {
+	return device.GetUsersContext(context.Background())
+}
+
+// GetUsersContext is like GetUsers but uses ctx for the request.
+func (device Device) GetUsersContext(ctx context.Context) ([]User, error) {
 	//create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetUsers xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
 	result := []User{}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetUsersResponse"`
+		User    []User
 	}
-
-	// parse response into interface
-	ifaceUsers, err := response.ValuesForPath("Envelope.Body.GetUsersResponse.User")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
 
-	for _, ifaceUser := range ifaceUsers {
-		if mapUser, ok := ifaceUser.(map[string]interface{}); ok {
-			user := User{}
-
-			user.Username = interfaceToString(mapUser["Username"])
-			user.Password = interfaceToString(mapUser["Password"])
-			user.UserLevel = interfaceToString(mapUser["UserLevel"])
-
-			result = append(result, user)
-		}
-	}
-
-	return result, nil
+	return append(result, response.User...), nil
 }
 
 func (device Device) SetUser(user User) error {
+	return device.SetUserContext(context.Background(), user)
+}
+
+// SetUserContext is like SetUser but uses ctx for the request.
+func (device Device) SetUserContext(ctx context.Context, user User) error {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<SetUser xmlns="http://www.onvif.org/ver10/device/wsdl"><User>
-					<Username xmlns="http://www.onvif.org/ver10/schema">` + user.Username + `</Username>
-					<Password xmlns="http://www.onvif.org/ver10/schema">` + user.Password + `</Password>
-					<UserLevel xmlns="http://www.onvif.org/ver10/schema">` + user.UserLevel + `</UserLevel>
+					<Username xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(user.Username) + `</Username>
+					<Password xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(user.Password) + `</Password>
+					<UserLevel xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(user.UserLevel) + `</UserLevel>
 				</User></SetUser>`,
 	}
-	// send soap request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
-	}
 
-	_, err = response.ValueForPath("Envelope.Body.SetUserResponse")
-	if err != nil {
-		return err
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl SetUserResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) DeleteUsers(usernames []string) error {
+	return device.DeleteUsersContext(context.Background(), usernames)
+}
+
+// DeleteUsersContext is like DeleteUsers but uses ctx for the request.
+func (device Device) DeleteUsersContext(ctx context.Context, usernames []string) error {
 	// create usernamebody
 	var usernameBody = ``
 	for _, username := range usernames {
-		usernameBody += `<Username>` + username + `</Username>`
+		usernameBody += `<Username>` + xmlEscape(username) + `</Username>`
 	}
 
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<DeleteUsers xmlns="http://www.onvif.org/ver10/device/wsdl">` + usernameBody + `</DeleteUsers>`,
 	}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl DeleteUsersResponse"`
 	}
-
-	_, err = response.ValueForPath("Envelope.Body.DeleteUsersResponse")
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) CreateUsers(users []User) error {
+	return device.CreateUsersContext(context.Background(), users)
+}
+
+// CreateUsersContext is like CreateUsers but uses ctx for the request.
+func (device Device) CreateUsersContext(ctx context.Context, users []User) error {
 	// create UserBody
 	var userBody = ``
 	for _, user := range users {
 		userBody += `<User>
-						<Username xmlns="http://www.onvif.org/ver10/schema">` + user.Username + `</Username>
-						<Password xmlns="http://www.onvif.org/ver10/schema">` + user.Password + `</Password>
-						<UserLevel xmlns="http://www.onvif.org/ver10/schema">` + user.UserLevel + `</UserLevel>
+						<Username xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(user.Username) + `</Username>
+						<Password xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(user.Password) + `</Password>
+						<UserLevel xmlns="http://www.onvif.org/ver10/schema">` + xmlEscape(user.UserLevel) + `</UserLevel>
 					 </User>`
 	}
 
@@ -1063,301 +938,163 @@ func (device Device) CreateUsers(users []User) error {
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<CreateUsers xmlns="http://www.onvif.org/ver10/device/wsdl">` + userBody + `</CreateUsers>`,
 	}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return err
-	}
-
-	_, err = response.ValueForPath("Envelope.Body.CreateUsersResponse")
-	if err != nil {
-		return err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl CreateUsersResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, device.XAddr, &response)
 }
 
 func (device Device) GetRelayOutputs() (RelayOutput, error) {
+	return device.GetRelayOutputsContext(context.Background())
+}
+
+// GetRelayOutputsContext is like GetRelayOutputs but uses ctx for the request.
+func (device Device) GetRelayOutputsContext(ctx context.Context) (RelayOutput, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetRelayOutputs xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
 	result := RelayOutput{}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName      xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetRelayOutputsResponse"`
+		RelayOutputs []RelayOutput
 	}
-
-	// parse response
-	ifaceRelayOutput, err := response.ValueForPath("Envelope.Body.GetRelayOutputsResponse.RelayOutputs")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
 
-	// parse into result
-	if mapRelayOutput, ok := ifaceRelayOutput.(map[string]interface{}); ok {
-		result.Token = interfaceToString(mapRelayOutput["-token"])
-		// parse properties
-		if mapProperties, ok := mapRelayOutput["Properties"].(map[string]interface{}); ok {
-			result.Properties.Mode = interfaceToString(mapProperties["Mode"])
-			result.Properties.DelayTime = interfaceToString(mapProperties["DelayTime"])
-			result.Properties.IdleState = interfaceToString(mapProperties["IdleState"])
-		}
+	if len(response.RelayOutputs) > 0 {
+		result = response.RelayOutputs[0]
 	}
 
 	return result, nil
 }
 
 func (device Device) GetZeroConfiguration() (NetworkZeroConfiguration, error) {
+	return device.GetZeroConfigurationContext(context.Background())
+}
+
+// GetZeroConfigurationContext is like GetZeroConfiguration but uses ctx for the request.
+func (device Device) GetZeroConfigurationContext(ctx context.Context) (NetworkZeroConfiguration, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetZeroConfiguration xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
 	}
 
-	result := NetworkZeroConfiguration{}
-
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName           xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetZeroConfigurationResponse"`
+		ZeroConfiguration NetworkZeroConfiguration
 	}
-
-	//parse response
-	ifaceNetworkZeroConfiguration, err := response.ValueForPath("Envelope.Body.GetZeroConfigurationResponse")
-	if err != nil {
-		return result, err
-	}
-
-	// parse interface
-	if mapNetworkZeroConfiguration, ok := ifaceNetworkZeroConfiguration.(map[string]interface{}); ok {
-		if mapZeroConfiguration, ok := mapNetworkZeroConfiguration["ZeroConfiguration"].(map[string]interface{}); ok {
-			result.InterfaceToken = interfaceToString(mapZeroConfiguration["InterfaceToken"])
-			result.Enabled = interfaceToBool(mapZeroConfiguration["Enabled"])
-			// parse addresses
-			for _, address := range mapZeroConfiguration["Addresses"].([]interface{}) {
-				result.Addresses = append(result.Addresses, interfaceToString(address))
-			}
-		}
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return NetworkZeroConfiguration{}, err
 	}
 
-	glog.Info(result)
+	result := response.ZeroConfiguration
+	device.logger().Log(LevelDebug, "zero configuration", "configuration", result)
 	return result, nil
 }
 
 func (device Device) GetServices() ([]Service, error) {
+	return device.GetServicesContext(context.Background())
+}
+
+// GetServicesContext is like GetServices but uses ctx for the request.
+func (device Device) GetServicesContext(ctx context.Context) ([]Service, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetServices xmlns="http://www.onvif.org/ver10/device/wsdl"><IncludeCapability>false</IncludeCapability></GetServices>`,
 	}
 
 	result := []Service{}
 
 	//send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name  `xml:"http://www.onvif.org/ver10/device/wsdl GetServicesResponse"`
+		Service []Service `xml:"Service"`
 	}
-
-	// parse response into interface
-	ifaceServices, err := response.ValuesForPath("Envelope.Body.GetServicesResponse.Service")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
 
-	// parse interface into struct
-	for _, ifaceService := range ifaceServices {
-		if mapService, ok := ifaceService.(map[string]interface{}); ok {
-			service := Service{}
-
-			service.Namespace = interfaceToString(mapService["Namespace"])
-			service.XAddr = interfaceToString(mapService["XAddr"])
-
-			// parse version
-			if mapVersion, ok := mapService["Version"].(map[string]interface{}); ok {
-				service.Version.Major = interfaceToInt(mapVersion["Major"])
-				service.Version.Minor = interfaceToInt(mapVersion["Minor"])
-			}
-			result = append(result, service)
-		}
-	}
-
-	return result, nil
+	return append(result, response.Service...), nil
 }
 
 func (device Device) GetServiceCapabilities() ([]Service, error) {
+	return device.GetServiceCapabilitiesContext(context.Background())
+}
+
+// GetServiceCapabilitiesContext is like GetServiceCapabilities but uses ctx for the request.
+func (device Device) GetServiceCapabilitiesContext(ctx context.Context) ([]Service, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetServices xmlns="http://www.onvif.org/ver10/device/wsdl"><IncludeCapability>true</IncludeCapability></GetServices>`,
 	}
 
 	result := []Service{}
 
 	//send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/device/wsdl GetServicesResponse"`
+		Service []struct {
+			Service
+			Capabilities struct {
+				// the capabilities of every kind of service, only the one
+				// matching the namespace is used
+				DeviceCapabilitiesService
+				MediaCapabilitiesService
+				EventsCapabilitiesService
+				ImagingCapabilitiesService
+				PTZCapabilitiesService
+			} `xml:"Capabilities>Capabilities"`
+		}
 	}
-
-	// parse response into interface
-	ifaceServices, err := response.ValuesForPath("Envelope.Body.GetServicesResponse.Service")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
 
-	// parse interface into struct
-	for _, ifaceService := range ifaceServices {
-		if mapService, ok := ifaceService.(map[string]interface{}); ok {
-			service := Service{}
-
-			service.Namespace = interfaceToString(mapService["Namespace"])
-			service.XAddr = interfaceToString(mapService["XAddr"])
-
-			// parse version
-			if mapVersion, ok := mapService["Version"].(map[string]interface{}); ok {
-				service.Version.Major = interfaceToInt(mapVersion["Major"])
-				service.Version.Minor = interfaceToInt(mapVersion["Minor"])
-			}
-
-			// parse capabilities
-			if mapCapabilities, ok := mapService["Capabilities"].(map[string]interface{}); ok {
-				if mapCapabilitiesAtrr, ok := mapCapabilities["Capabilities"].(map[string]interface{}); ok {
-					if strings.Index(service.Namespace, "device") > -1 { // Device Capabilities
-						deviceCapabilities := DeviceCapabilitiesService{}
-						// parse network
-						network := DeviceNetworkCapabilitiesService{}
-						if mapNetwork, ok := mapCapabilitiesAtrr["Network"].(map[string]interface{}); ok {
-							network.DynDNS = interfaceToBool(mapNetwork["-DynDNS"])
-							network.IPFilter = interfaceToBool(mapNetwork["-IPFilter"])
-							network.IPVersion6 = interfaceToBool(mapNetwork["-IPVersion6"])
-							network.NTP = interfaceToInt(mapNetwork["-NTP"])
-							network.ZeroConfiguration = interfaceToBool(mapNetwork["-ZeroConfiguration"])
-						}
-						deviceCapabilities.Network = network
-
-						// parse System
-						system := DeviceSystemCapabilitiesService{}
-						if mapSystem, ok := mapCapabilitiesAtrr["System"].(map[string]interface{}); ok {
-							system.DiscoveryBye = interfaceToBool(mapSystem["-DiscoveryBye"])
-							system.DiscoveryResolve = interfaceToBool(mapSystem["-DiscoveryResolve"])
-							system.FirmwareUpgrade = interfaceToBool(mapSystem["-FirmwareUpgrade"])
-							system.RemoteDiscovery = interfaceToBool(mapSystem["-RemoteDiscovery"])
-							system.SystemBackup = interfaceToBool(mapSystem["-SystemBackup"])
-							system.SystemLogging = interfaceToBool(mapSystem["-SystemLogging"])
-						}
-						deviceCapabilities.System = system
-
-						// parse Security
-						security := DeviceSecurityCapabilitiesService{}
-						if mapSecurity, ok := mapCapabilitiesAtrr["Security"].(map[string]interface{}); ok {
-							security.AccesssPolicyConfig = interfaceToBool(mapSecurity["-AccessPolicyConfig"])
-							security.DefaultAccessPolicy = interfaceToBool(mapSecurity["-DefaultAccessPolicy"])
-							security.Dot1X = interfaceToBool(mapSecurity["-Dot1X"])
-							security.HttpDigest = interfaceToBool(mapSecurity["-HttpDigest"])
-							security.KerberosToken = interfaceToBool(mapSecurity["-KerberosToken"])
-							security.OnboardKeyGeneration = interfaceToBool(mapSecurity["-OnboardKeyGeneration"])
-							security.RELToken = interfaceToBool(mapSecurity["-RELToken"])
-							security.RemoteUserHandling = interfaceToBool(mapSecurity["-RemoteUserHandling"])
-							security.SAMLToken = interfaceToBool(mapSecurity["-SAMLToken"])
-							security.TLS10 = interfaceToBool(mapSecurity["-TLS1.0"])
-							security.TLS11 = interfaceToBool(mapSecurity["-TLS1.1"])
-							security.TLS12 = interfaceToBool(mapSecurity["-TLS1.2"])
-							security.UsernameToken = interfaceToBool(mapSecurity["-UsernameToken"])
-							security.X509Token = interfaceToBool(mapSecurity["-X.509Token"])
-						}
-						deviceCapabilities.Security = security
-
-						// add to service
-						capabilities := CapabilitiesService{}
-						capabilities.Name = "device"
-						capabilities.Capabilities = deviceCapabilities
-						service.Capabilities = capabilities
-					} else if strings.Index(service.Namespace, "media") > -1 { // Media Capabilities
-						mediaCapabilities := MediaCapabilitiesService{}
-
-						mediaCapabilities.OSD = interfaceToBool(mapCapabilitiesAtrr["-OSD"])
-						mediaCapabilities.Rotation = interfaceToBool(mapCapabilitiesAtrr["-Rotation"])
-						mediaCapabilities.SnapshotUri = interfaceToBool(mapCapabilitiesAtrr["-SnapshotUri"])
-						mediaCapabilities.VideoSourceMode = interfaceToBool(mapCapabilitiesAtrr["-VideoSourceMode"])
-
-						// parse ProfileCapabilities
-						if mapProfileCapabilities, ok := mapCapabilitiesAtrr["ProfileCapabilities"].(map[string]interface{}); ok {
-							mediaCapabilities.ProfileCapabilities.MaximumNumberOfProfiles = interfaceToInt(mapProfileCapabilities["MaximumNumberOfProfiles"])
-						}
-
-						// parse Streaming Capabilities
-						if mapStreamingCapabilities, ok := mapCapabilitiesAtrr["StreamingCapabilities"].(map[string]interface{}); ok {
-							streamingCapabilities := MediaStreamingCapabilitiesService{}
-
-							streamingCapabilities.NonAggregateControl = interfaceToBool(mapStreamingCapabilities["-NonAggregateControl"])
-							streamingCapabilities.NoRTSPStreaming = interfaceToBool(mapStreamingCapabilities["-NoRTSPStreaming"])
-							streamingCapabilities.RTP_RTSP_TCP = interfaceToBool(mapStreamingCapabilities["-RTP_RTSP_TCP"])
-							streamingCapabilities.RTP_TCP = interfaceToBool(mapStreamingCapabilities["-RTP_TCP"])
-							streamingCapabilities.RTPMulticast = interfaceToBool(mapStreamingCapabilities["-RTPMulticast"])
-
-							mediaCapabilities.StreamingCapabilities = streamingCapabilities
-						}
-
-						// add to service
-						capabilities := CapabilitiesService{}
-						capabilities.Name = "media"
-						capabilities.Capabilities = mediaCapabilities
-						service.Capabilities = capabilities
-					} else if strings.Index(service.Namespace, "events") > -1 { // Events Capabilities
-						eventsCapabilities := EventsCapabilitiesService{}
-
-						eventsCapabilities.MaxNotificationProducers = interfaceToInt(mapCapabilitiesAtrr["-MaxNotificationProducers"])
-						eventsCapabilities.MaxPullPoints = interfaceToInt(mapCapabilitiesAtrr["-MaxPullPoints"])
-						eventsCapabilities.PersistentNotificationStorage = interfaceToBool(mapCapabilitiesAtrr["-PersistentNotificationStorage"])
-						eventsCapabilities.WSPausableSubscriptionManagerInterfaceSupport = interfaceToBool(mapCapabilitiesAtrr["-WSPausableSubscriptionManagerInterfaceSupport"])
-						eventsCapabilities.WSPullPointSupport = interfaceToBool(mapCapabilitiesAtrr["-WSPullPointSupport"])
-						eventsCapabilities.WSSubscriptionPolicySupport = interfaceToBool(mapCapabilitiesAtrr["-WSSubscriptionPolicySupport"])
-
-						// add to service
-						capabilities := CapabilitiesService{}
-						capabilities.Name = "events"
-						capabilities.Capabilities = eventsCapabilities
-						service.Capabilities = capabilities
-					} else if strings.Index(service.Namespace, "imaging") > -1 { // Imaging Capabilities
-						imagingCapabilities := ImagingCapabilitiesService{}
-
-						imagingCapabilities.ImageStabilization = interfaceToBool(mapCapabilitiesAtrr["-ImageStabilization"])
-
-						// add to service
-						capabilities := CapabilitiesService{}
-						capabilities.Name = "imaging"
-						capabilities.Capabilities = imagingCapabilities
-						service.Capabilities = capabilities
-					} else if strings.Index(service.Namespace, "ptz") > -1 { // PTZ Capabilities
-						ptzCapabilities := PTZCapabilitiesService{}
-
-						ptzCapabilities.EFlip = interfaceToBool(mapCapabilitiesAtrr["-EFlip"])
-						ptzCapabilities.GetCompatibleConfigurations = interfaceToBool(mapCapabilitiesAtrr["-GetCompatibleConfigurations"])
-						ptzCapabilities.Reverse = interfaceToBool(mapCapabilitiesAtrr["-Reverse"])
-
-						// add to service
-						capabilities := CapabilitiesService{}
-						capabilities.Name = "PTZ"
-						capabilities.Capabilities = ptzCapabilities
-						service.Capabilities = capabilities
-					}
-				}
-			}
-			result = append(result, service)
+	for _, item := range response.Service {
+		service := item.Service
+		capabilities := item.Capabilities
+
+		// parse capabilities
+		if strings.Index(service.Namespace, "device") > -1 { // Device Capabilities
+			service.Capabilities = CapabilitiesService{Name: "device", Capabilities: capabilities.DeviceCapabilitiesService}
+		} else if strings.Index(service.Namespace, "media") > -1 { // Media Capabilities
+			service.Capabilities = CapabilitiesService{Name: "media", Capabilities: capabilities.MediaCapabilitiesService}
+		} else if strings.Index(service.Namespace, "events") > -1 { // Events Capabilities
+			service.Capabilities = CapabilitiesService{Name: "events", Capabilities: capabilities.EventsCapabilitiesService}
+		} else if strings.Index(service.Namespace, "imaging") > -1 { // Imaging Capabilities
+			service.Capabilities = CapabilitiesService{Name: "imaging", Capabilities: capabilities.ImagingCapabilitiesService}
+		} else if strings.Index(service.Namespace, "ptz") > -1 { // PTZ Capabilities
+			service.Capabilities = CapabilitiesService{Name: "PTZ", Capabilities: capabilities.PTZCapabilitiesService}
 		}
+		result = append(result, service)
 	}
 
 	return result, nil
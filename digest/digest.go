@@ -13,10 +13,11 @@
 // limitations under the License.
 
 // The digest package provides an implementation of http.RoundTripper that takes
-// care of HTTP Digest Authentication (http://www.ietf.org/rfc/rfc2617.txt).
-// This only implements the MD5 and "auth" portions of the RFC, but that covers
-// the majority of avalible server side implementations including apache web
-// server.
+// care of HTTP Digest Authentication (http://www.ietf.org/rfc/rfc7616.txt).
+// It implements the MD5, SHA-256 and SHA-512-256 algorithms and their session
+// variants, the "auth" and "auth-int" qualities of protection and username
+// hashing. When the server offers several challenges, the strongest one is
+// used.
 //
 // Example usage:
 //
@@ -44,17 +45,21 @@
 package digest
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"crypto/rand"
-	"crypto/tls"
+	"crypto/sha256"
+	"crypto/sha512"
 	"errors"
 	"fmt"
-	"github.com/golang/glog"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -65,11 +70,19 @@ var (
 )
 
 // Transport is an implementation of http.RoundTripper that takes care of http
-// digest authentication.
+// digest authentication. The last challenge received from the server is kept
+// so that following requests are authorized up front, without paying for
+// another 401 round trip. The credentials of a request set with
+// WithCredentials replace Username and Password, so one Transport may serve
+// several users of the same server.
 type Transport struct {
 	Username  string
 	Password  string
 	Transport http.RoundTripper
+
+	mu        sync.Mutex
+	challenge *challenge
+	nc        int
 }
 
 // NewTransport creates a new digest transport using the http.DefaultTransport.
@@ -85,11 +98,8 @@ func NewTransport(username, password string) *Transport {
 			KeepAlive: 30 * time.Second,
 			DualStack: true,
 		}).DialContext,
-		ForceAttemptHTTP2: true,
-		MaxIdleConns:      100,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
@@ -97,6 +107,20 @@ func NewTransport(username, password string) *Transport {
 	return t
 }
 
+// credentialsKey is the context key of the credentials of a request
+type credentialsKey struct{}
+
+// requestCredentials are the credentials of a request
+type requestCredentials struct {
+	username, password string
+}
+
+// WithCredentials returns a copy of ctx carrying the username and password
+// used by Transport for the requests made with it.
+func WithCredentials(ctx context.Context, username, password string) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, requestCredentials{username, password})
+}
+
 type challenge struct {
 	Realm     string
 	Domain    string
@@ -105,44 +129,180 @@ type challenge struct {
 	Stale     string
 	Algorithm string
 	Qop       string
+	Userhash  bool
 }
 
-func parseChallenge(input string) (*challenge, error) {
-	const ws = " \n\r\t"
-	const qs = `"`
-	s := strings.Trim(input, ws)
-	if !strings.HasPrefix(s, "Digest ") {
+// algorithms lists the supported digest algorithms, strongest first.
+var algorithms = []string{
+	"SHA-512-256", "SHA-512-256-sess",
+	"SHA-256", "SHA-256-sess",
+	"MD5", "MD5-sess",
+}
+
+// hashFunc returns the hash of a digest algorithm and whether it is a
+// session variant.
+func hashFunc(algorithm string) (func() hash.Hash, bool, error) {
+	name := strings.ToUpper(algorithm)
+	sess := strings.HasSuffix(name, "-SESS")
+	name = strings.TrimSuffix(name, "-SESS")
+	switch name {
+	case "MD5":
+		return md5.New, sess, nil
+	case "SHA-256":
+		return sha256.New, sess, nil
+	case "SHA-512-256":
+		return sha512.New512_256, sess, nil
+	}
+	return nil, false, ErrAlgNotImplemented
+}
+
+// qopOptions returns the quality of protection values offered by a
+// challenge.
+func (c *challenge) qopOptions() []string {
+	var options []string
+	for _, qop := range strings.Split(c.Qop, ",") {
+		if qop = strings.TrimSpace(qop); qop != "" {
+			options = append(options, qop)
+		}
+	}
+	return options
+}
+
+// messageQop picks "auth" over "auth-int" among the offered values. It
+// returns "" for RFC 2069 challenges, which have no qop.
+func (c *challenge) messageQop() (string, error) {
+	options := c.qopOptions()
+	if len(options) == 0 {
+		return "", nil
+	}
+	for _, preferred := range []string{"auth", "auth-int"} {
+		for _, qop := range options {
+			if strings.EqualFold(qop, preferred) {
+				return preferred, nil
+			}
+		}
+	}
+	return "", ErrAlgNotImplemented
+}
+
+// supported reports whether the challenge can be answered
+func (c *challenge) supported() bool {
+	if _, _, err := hashFunc(c.Algorithm); err != nil {
+		return false
+	}
+	_, err := c.messageQop()
+	return err == nil
+}
+
+// parseChallenge returns the strongest supported Digest challenge among the
+// WWW-Authenticate header values. A single value may hold several
+// challenges, e.g. one per algorithm.
+func parseChallenge(inputs ...string) (*challenge, error) {
+	var challenges []*challenge
+	for _, input := range inputs {
+		challenges = append(challenges, parseChallenges(input)...)
+	}
+	if len(challenges) == 0 {
 		return nil, ErrBadChallenge
 	}
-	s = strings.Trim(s[7:], ws)
-	sl := strings.Split(s, ", ")
-	c := &challenge{
-		Algorithm: "MD5",
+
+	for _, algorithm := range algorithms {
+		for _, c := range challenges {
+			if strings.EqualFold(c.Algorithm, algorithm) && c.supported() {
+				return c, nil
+			}
+		}
 	}
-	var r []string
-	for i := range sl {
-		r = strings.SplitN(sl[i], "=", 2)
-		switch r[0] {
+	return nil, ErrAlgNotImplemented
+}
+
+// parseChallenges parses the Digest challenges of a WWW-Authenticate header
+// value as defined in RFC 7235. Challenges of other schemes and unknown
+// parameters are skipped.
+func parseChallenges(input string) []*challenge {
+	var challenges []*challenge
+	var c *challenge
+	s := input
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		if s == "" {
+			break
+		}
+
+		// Read a token, which is either an auth scheme or a parameter name
+		end := strings.IndexAny(s, " \t\r\n,=")
+		if end < 0 {
+			end = len(s)
+		}
+		token := s[:end]
+		s = strings.TrimLeft(s[end:], " \t\r\n")
+
+		if !strings.HasPrefix(s, "=") {
+			// New challenge
+			c = nil
+			if strings.EqualFold(token, "Digest") {
+				c = &challenge{Algorithm: "MD5"}
+				challenges = append(challenges, c)
+			}
+			continue
+		}
+
+		// Parameter, its value is a token or a quoted string
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			value, s = readQuoted(s)
+		} else {
+			end = strings.IndexAny(s, " \t\r\n,")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		if c == nil {
+			continue
+		}
+
+		switch strings.ToLower(token) {
 		case "realm":
-			c.Realm = strings.Trim(r[1], qs)
+			c.Realm = value
 		case "domain":
-			c.Domain = strings.Trim(r[1], qs)
+			c.Domain = value
 		case "nonce":
-			c.Nonce = strings.Trim(r[1], qs)
+			c.Nonce = value
 		case "opaque":
-			c.Opaque = strings.Trim(r[1], qs)
+			c.Opaque = value
 		case "stale":
-			c.Stale = strings.Trim(r[1], qs)
+			c.Stale = value
 		case "algorithm":
-			c.Algorithm = strings.Trim(r[1], qs)
+			c.Algorithm = value
 		case "qop":
-			//TODO(gavaletz) should be an array of strings?
-			c.Qop = strings.Trim(r[1], qs)
+			c.Qop = value
+		case "userhash":
+			c.Userhash = strings.EqualFold(value, "true")
+		}
+	}
+	return challenges
+}
+
+// readQuoted reads a quoted string at the start of s, handling backslash
+// escapes, and returns its value and the rest of s.
+func readQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
 		default:
-			return nil, ErrBadChallenge
+			b.WriteByte(s[i])
 		}
 	}
-	return c, nil
+	return b.String(), ""
 }
 
 type credentials struct {
@@ -155,62 +315,87 @@ type credentials struct {
 	Opaque     string
 	MessageQop string
 	NonceCount int
+	Userhash   bool
 	method     string
 	password   string
+	body       func() (io.ReadCloser, error)
+	hash       func() hash.Hash
+	sess       bool
 }
 
-func h(data string) string {
-	hf := md5.New()
+func (c *credentials) h(data string) string {
+	hf := c.hash()
 	io.WriteString(hf, data)
 	return fmt.Sprintf("%x", hf.Sum(nil))
 }
 
-func kd(secret, data string) string {
-	return h(fmt.Sprintf("%s:%s", secret, data))
+func (c *credentials) kd(secret, data string) string {
+	return c.h(fmt.Sprintf("%s:%s", secret, data))
 }
 
 func (c *credentials) ha1() string {
-	return h(fmt.Sprintf("%s:%s:%s", c.Username, c.Realm, c.password))
+	ha1 := c.h(fmt.Sprintf("%s:%s:%s", c.Username, c.Realm, c.password))
+	if c.sess {
+		return c.h(fmt.Sprintf("%s:%s:%s", ha1, c.Nonce, c.Cnonce))
+	}
+	return ha1
 }
 
-func (c *credentials) ha2() string {
-	return h(fmt.Sprintf("%s:%s", c.method, c.DigestURI))
+func (c *credentials) ha2() (string, error) {
+	if c.MessageQop == "auth-int" {
+		bodyHash := c.h("")
+		if c.body != nil {
+			body, err := c.body()
+			if err != nil {
+				return "", err
+			}
+			buf, err := ioutil.ReadAll(body)
+			body.Close()
+			if err != nil {
+				return "", err
+			}
+			bodyHash = c.h(string(buf))
+		}
+		return c.h(fmt.Sprintf("%s:%s:%s", c.method, c.DigestURI, bodyHash)), nil
+	}
+	return c.h(fmt.Sprintf("%s:%s", c.method, c.DigestURI)), nil
 }
 
 func (c *credentials) resp(cnonce string) (string, error) {
 	c.NonceCount++
-	if c.MessageQop == "auth" {
-		if cnonce != "" {
-			c.Cnonce = cnonce
-		} else {
-			b := make([]byte, 8)
-			io.ReadFull(rand.Reader, b)
-			c.Cnonce = fmt.Sprintf("%x", b)[:16]
-		}
-		return kd(c.ha1(), fmt.Sprintf("%s:%08x:%s:%s:%s",
-			c.Nonce, c.NonceCount, c.Cnonce, c.MessageQop, c.ha2())), nil
-	} else if c.MessageQop == "" {
-		return kd(c.ha1(), fmt.Sprintf("%s:%s", c.Nonce, c.ha2())), nil
+	if cnonce != "" {
+		c.Cnonce = cnonce
+	} else {
+		b := make([]byte, 8)
+		io.ReadFull(rand.Reader, b)
+		c.Cnonce = fmt.Sprintf("%x", b)[:16]
 	}
-	return "", ErrAlgNotImplemented
+	ha2, err := c.ha2()
+	if err != nil {
+		return "", err
+	}
+	if c.MessageQop == "" {
+		return c.kd(c.ha1(), fmt.Sprintf("%s:%s", c.Nonce, ha2)), nil
+	}
+	return c.kd(c.ha1(), fmt.Sprintf("%s:%08x:%s:%s:%s",
+		c.Nonce, c.NonceCount, c.Cnonce, c.MessageQop, ha2)), nil
 }
 
 func (c *credentials) authorize() (string, error) {
-	// Note that this is only implemented for MD5 and NOT MD5-sess.
-	// MD5-sess is rarely supported and those that do are a big mess.
-	if c.Algorithm != "MD5" {
-		return "", ErrAlgNotImplemented
-	}
-	// Note that this is NOT implemented for "qop=auth-int".  Similarly the
-	// auth-int server side implementations that do exist are a mess.
-	if c.MessageQop != "auth" && c.MessageQop != "" {
-		return "", ErrAlgNotImplemented
+	var err error
+	c.hash, c.sess, err = hashFunc(c.Algorithm)
+	if err != nil {
+		return "", err
 	}
 	resp, err := c.resp("")
 	if err != nil {
-		return "", ErrAlgNotImplemented
+		return "", err
 	}
-	sl := []string{fmt.Sprintf(`username="%s"`, c.Username)}
+	username := c.Username
+	if c.Userhash {
+		username = c.h(fmt.Sprintf("%s:%s", c.Username, c.Realm))
+	}
+	sl := []string{fmt.Sprintf(`username="%s"`, username)}
 	sl = append(sl, fmt.Sprintf(`realm="%s"`, c.Realm))
 	sl = append(sl, fmt.Sprintf(`nonce="%s"`, c.Nonce))
 	sl = append(sl, fmt.Sprintf(`uri="%s"`, c.DigestURI))
@@ -226,62 +411,150 @@ func (c *credentials) authorize() (string, error) {
 		sl = append(sl, fmt.Sprintf("nc=%08x", c.NonceCount))
 		sl = append(sl, fmt.Sprintf(`cnonce="%s"`, c.Cnonce))
 	}
+	if c.Userhash {
+		sl = append(sl, "userhash=true")
+	}
 	return fmt.Sprintf("Digest %s", strings.Join(sl, ", ")), nil
 }
 
 func (t *Transport) newCredentials(req *http.Request, c *challenge) *credentials {
+	username, password := t.Username, t.Password
+	if cr, ok := req.Context().Value(credentialsKey{}).(requestCredentials); ok {
+		username, password = cr.username, cr.password
+	}
+
+	qop, _ := c.messageQop()
 	return &credentials{
-		Username:   t.Username,
+		Username:   username,
 		Realm:      c.Realm,
 		Nonce:      c.Nonce,
 		DigestURI:  req.URL.RequestURI(),
 		Algorithm:  c.Algorithm,
 		Opaque:     c.Opaque,
-		MessageQop: c.Qop, // "auth" must be a single value
+		MessageQop: qop,
 		NonceCount: 0,
+		Userhash:   c.Userhash,
 		method:     req.Method,
-		password:   t.Password,
+		password:   password,
+		body:       req.GetBody,
 	}
 }
 
+// cachedAuthorization builds an Authorization header from the cached
+// challenge, incrementing the nonce count. It returns "" when there is no
+// usable challenge yet.
+func (t *Transport) cachedAuthorization(req *http.Request) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.challenge == nil {
+		return ""
+	}
+	cr := t.newCredentials(req, t.challenge)
+	cr.NonceCount = t.nc
+	auth, err := cr.authorize()
+	if err != nil {
+		t.challenge = nil
+		return ""
+	}
+	t.nc = cr.NonceCount
+	return auth
+}
+
+// authorizeChallenge parses the challenge of a 401 response, stores it for
+// later requests and returns the matching Authorization header.
+func (t *Transport) authorizeChallenge(req *http.Request, chal []string) (string, error) {
+	c, err := parseChallenge(chal...)
+	if err != nil {
+		return "", err
+	}
+	cr := t.newCredentials(req, c)
+	auth, err := cr.authorize()
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	t.challenge = c
+	t.nc = cr.NonceCount
+	t.mu.Unlock()
+	return auth, nil
+}
+
+// cloneRequest returns a copy of req with its own header map and a fresh
+// body, so that the request can be sent more than once.
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Header = make(http.Header)
+	for k, s := range req.Header {
+		req2.Header[k] = s
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req2.Body = body
+	}
+	return req2, nil
+}
+
 // RoundTrip makes a request expecting a 401 response that will require digest
 // authentication.  It creates the credentials it needs and makes a follow-up
-// request.
+// request. When a challenge from a previous request is cached, the request
+// is authorized immediately and the challenge is only renewed if the server
+// rejects it.
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if t.Transport == nil {
 		return nil, ErrNilTransport
 	}
 
+	// Make sure the body can be replayed for the authenticated request.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		buf, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(req.Context())
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(buf)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+
 	// Copy the request so we don't modify the input.
-	req2 := new(http.Request)
-	*req2 = *req
-	req2.Header = make(http.Header)
-	for k, s := range req.Header {
-		req2.Header[k] = s
+	req1, err := cloneRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Make a request to get the 401 that contains the challenge.
-	resp, err := t.Transport.RoundTrip(req)
+	// Reuse the cached challenge, otherwise make a request to get the 401
+	// that contains the challenge.
+	if auth := t.cachedAuthorization(req1); auth != "" {
+		req1.Header.Set("Authorization", auth)
+	}
+	resp, err := t.Transport.RoundTrip(req1)
 	if err != nil || resp.StatusCode != 401 {
 		return resp, err
 	}
-	chal := resp.Header.Get("WWW-Authenticate")
-	c, err := parseChallenge(chal)
+
+	req2, err := cloneRequest(req)
 	if err != nil {
-		// Read response body
-		responseBody, errR := ioutil.ReadAll(resp.Body)
-		if errR == nil {
-			glog.Infof("Onvif response: %s", string(responseBody))
-			return resp, err
-		}
-		return resp, errR
+		return resp, err
 	}
 
 	// Form credentials based on the challenge.
-	cr := t.newCredentials(req2, c)
-	auth, err := cr.authorize()
+	chal := resp.Header["Www-Authenticate"]
+	auth, err := t.authorizeChallenge(req2, chal)
+	if err == ErrBadChallenge {
+		// The server does not offer digest authentication, hand its 401
+		// response back to the caller.
+		return resp, nil
+	}
 	if err != nil {
-		return resp, err
+		resp.Body.Close()
+		return nil, err
 	}
 
 	// We'll no longer use the initial response, so close it
@@ -0,0 +1,196 @@
+package digest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func hexHash(newHash func() hash.Hash, data string) string {
+	hf := newHash()
+	hf.Write([]byte(data))
+	return fmt.Sprintf("%x", hf.Sum(nil))
+}
+
+// parseAuthorization splits a Digest Authorization header into its fields.
+func parseAuthorization(header string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(header, "Digest "), ", ") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return fields
+}
+
+// digestServer is protected by digest authentication with qop=auth. It
+// records how many requests it received and the last nonce count.
+type digestServer struct {
+	user, password string
+	challenge      string
+	newHash        func() hash.Hash
+	sess           bool
+	requests       int
+	lastNC         string
+	lastAuth       map[string]string
+}
+
+const testRealm, testNonce = "onvif", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+
+func (server *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.requests++
+	body, _ := ioutil.ReadAll(r.Body)
+
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		w.Header().Set("WWW-Authenticate", server.challenge)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f := parseAuthorization(auth)
+	server.lastAuth = f
+	username := server.user
+	if f["userhash"] == "true" {
+		username = hexHash(server.newHash, server.user+":"+testRealm)
+	}
+	ha1 := hexHash(server.newHash, server.user+":"+testRealm+":"+server.password)
+	if server.sess {
+		ha1 = hexHash(server.newHash, ha1+":"+testNonce+":"+f["cnonce"])
+	}
+	ha2 := hexHash(server.newHash, r.Method+":"+f["uri"])
+	expected := hexHash(server.newHash, ha1+":"+testNonce+":"+f["nc"]+":"+f["cnonce"]+":auth:"+ha2)
+	if f["username"] != username || f["response"] != expected {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	server.lastNC = f["nc"]
+	w.Write(body)
+}
+
+func TestTransportCachesChallenge(t *testing.T) {
+	server := &digestServer{
+		user:      "admin",
+		password:  "secret",
+		challenge: fmt.Sprintf(`Digest realm="%s", nonce="%s", qop="auth"`, testRealm, testNonce),
+		newHash:   md5.New,
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tr := &Transport{Username: "admin", Password: "secret", Transport: http.DefaultTransport}
+	for i := 1; i <= 3; i++ {
+		req, _ := http.NewRequest("POST", ts.URL+"/onvif/device_service", strings.NewReader("payload"))
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, resp.StatusCode)
+		}
+		if string(body) != "payload" {
+			t.Fatalf("request %d: body was not replayed, got %q", i, body)
+		}
+		if want := fmt.Sprintf("%08x", i); server.lastNC != want {
+			t.Errorf("request %d: nc = %s, want %s", i, server.lastNC, want)
+		}
+	}
+
+	// One challenge round trip, then a single request per call
+	if server.requests != 4 {
+		t.Errorf("server received %d requests, want 4", server.requests)
+	}
+}
+
+func TestTransportWithCredentials(t *testing.T) {
+	server := &digestServer{
+		user:      "admin",
+		password:  "secret",
+		challenge: fmt.Sprintf(`Digest realm="%s", nonce="%s", qop="auth"`, testRealm, testNonce),
+		newHash:   md5.New,
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// The credentials of the requests replace those of the transport
+	tr := &Transport{Username: "admin", Password: "wrong", Transport: http.DefaultTransport}
+	for _, test := range []struct {
+		password string
+		status   int
+	}{
+		{"guess", http.StatusUnauthorized},
+		{"secret", http.StatusOK},
+		{"guess", http.StatusUnauthorized},
+	} {
+		req, _ := http.NewRequest("POST", ts.URL+"/onvif/device_service", strings.NewReader("payload"))
+		req = req.WithContext(WithCredentials(req.Context(), "admin", test.password))
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("password %q: status %d, want %d", test.password, resp.StatusCode, test.status)
+		}
+	}
+}
+
+func TestTransportPicksStrongestChallenge(t *testing.T) {
+	server := &digestServer{
+		user:     "admin",
+		password: "secret",
+		challenge: fmt.Sprintf(`Digest realm="%s", nonce="%s", qop="auth,auth-int", algorithm=MD5, charset=UTF-8, `+
+			`Digest realm="%s", nonce="%s", qop="auth,auth-int", algorithm=SHA-256-sess, userhash=true`,
+			testRealm, testNonce, testRealm, testNonce),
+		newHash: sha256.New,
+		sess:    true,
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tr := &Transport{Username: "admin", Password: "secret", Transport: http.DefaultTransport}
+	req, _ := http.NewRequest("POST", ts.URL+"/onvif/device_service", strings.NewReader("payload"))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, authorization %v", resp.StatusCode, server.lastAuth)
+	}
+	if server.lastAuth["algorithm"] != "SHA-256-sess" || server.lastAuth["qop"] != "auth" {
+		t.Errorf("unexpected authorization %v", server.lastAuth)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	c, err := parseChallenge(
+		`Basic realm="camera"`,
+		`Digest realm="a, \"quoted\" realm", nonce="abc", qop="auth-int", algorithm=SHA-512-256, foo=bar`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Realm != `a, "quoted" realm` || c.Nonce != "abc" || c.Algorithm != "SHA-512-256" {
+		t.Errorf("unexpected challenge %+v", c)
+	}
+	if qop, _ := c.messageQop(); qop != "auth-int" {
+		t.Errorf("qop = %q, want auth-int", qop)
+	}
+
+	if _, err = parseChallenge(`Digest realm="a", nonce="abc", algorithm=SHA-1`); err != ErrAlgNotImplemented {
+		t.Errorf("expected ErrAlgNotImplemented, got %v", err)
+	}
+	if _, err = parseChallenge(`Basic realm="camera"`); err != ErrBadChallenge {
+		t.Errorf("expected ErrBadChallenge, got %v", err)
+	}
+}
@@ -1,89 +1,496 @@
 package onvif
 
 import (
+	"encoding/xml"
 	"errors"
-	"github.com/clbanning/mxj"
-	"github.com/golang/glog"
 	"github.com/google/uuid"
+	"math/rand"
 	"net"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 var errWrongDiscoveryResponse = errors.New("Response is not related to discovery request ")
 
-// StartDiscovery send a WS-Discovery message and wait for all matching device to respond
-func StartDiscoveryOn(interfaceName string, duration time.Duration) ([]Device, error) {
-	itf, err := net.InterfaceByName(interfaceName) //here your interface
+// Retransmission of UDP messages, from the SOAP-over-UDP specification.
+// Multicast messages are repeated more as they are more likely to be lost.
+const (
+	unicastUDPRepeat   = 1
+	multicastUDPRepeat = 2
+	udpMinDelay        = 50 * time.Millisecond
+	udpMaxDelay        = 250 * time.Millisecond
+	udpUpperDelay      = 500 * time.Millisecond
+)
 
-	if err != nil {
-		return []Device{}, err
-	}
-
-	item, _ := itf.Addrs()
-	var ip net.IP
-	for _, addr := range item {
-		switch v := addr.(type) {
-		case *net.IPNet:
-			if !v.IP.IsLoopback() {
-				if v.IP.To4() != nil { //Verify if IP is IPV4
-					ip = v.IP
-				}
-			}
+// DiscoveredDevice is a device, or a channel of a recorder, which answered a
+// WS-Discovery Probe or announced itself
+type DiscoveredDevice struct {
+	// EndpointReference is the stable address of the device, usually an
+	// urn:uuid: URI
+	EndpointReference string `json:"endpointReference"`
+	// ID is the EndpointReference without its urn:uuid: prefix
+	ID string `json:"id"`
+	// Types are the qualified names of the types of the device, such as
+	// dn:NetworkVideoTransmitter
+	Types []string `json:"types"`
+	// Scopes are the scope URIs of the device
+	Scopes []string `json:"scopes"`
+	// ScopeValues maps the categories of the onvif:// scopes, such as name,
+	// hardware, location, type or Profile, to their unescaped values
+	ScopeValues map[string][]string `json:"scopeValues"`
+	// XAddrs are the addresses of the device service, over IPv4, IPv6 or
+	// HTTPS
+	XAddrs []string `json:"xAddrs"`
+	// MetadataVersion is incremented by the device when its metadata change
+	MetadataVersion uint `json:"metadataVersion"`
+}
+
+// onvifScopePrefix starts the scopes defined by ONVIF
+const onvifScopePrefix = "onvif://www.onvif.org/"
+
+// scopeValues parses the onvif:// scopes into their categories and values,
+// e.g. onvif://www.onvif.org/location/city/Hanoi gives "city/Hanoi" for
+// "location".
+func scopeValues(scopes []string) map[string][]string {
+	values := make(map[string][]string)
+	for _, scope := range scopes {
+		if !strings.HasPrefix(scope, onvifScopePrefix) {
+			continue
+		}
+
+		path := strings.TrimPrefix(scope, onvifScopePrefix)
+		separator := strings.Index(path, "/")
+		if separator <= 0 {
+			continue
+		}
+
+		category, value := path[:separator], path[separator+1:]
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		values[category] = append(values[category], value)
+	}
+	return values
+}
+
+// scope returns the first value of the onvif scope category
+func (device DiscoveredDevice) scope(category string) string {
+	if values := device.ScopeValues[category]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Name returns the name of the device, from its name scope
+func (device DiscoveredDevice) Name() string {
+	return strings.Replace(device.scope("name"), "_", " ", -1)
+}
+
+// Hardware returns the model of the device, from its hardware scope
+func (device DiscoveredDevice) Hardware() string {
+	return device.scope("hardware")
+}
+
+// Location returns the first location scope of the device
+func (device DiscoveredDevice) Location() string {
+	return device.scope("location")
+}
+
+// Profiles returns the ONVIF profiles the device conforms to, such as
+// Streaming, G, T or S
+func (device DiscoveredDevice) Profiles() []string {
+	return device.ScopeValues["Profile"]
+}
+
+// Device returns a Device for the first XAddr of the discovered device, as
+// returned by StartDiscovery
+func (device DiscoveredDevice) Device() Device {
+	result := Device{
+		ID:   device.ID,
+		Name: device.Name(),
+	}
+	if len(device.XAddrs) > 0 {
+		result.XAddr = device.XAddrs[0]
+	}
+	return result
+}
+
+// IsDiscoveryProxy reports whether the device is a WS-Discovery proxy,
+// through which the devices of a managed network are found
+func (device DiscoveredDevice) IsDiscoveryProxy() bool {
+	for _, qualifiedName := range device.Types {
+		if qualifiedName[strings.Index(qualifiedName, ":")+1:] == "DiscoveryProxy" {
+			return true
 		}
 	}
+	return false
+}
 
-	if ip == nil {
-		return []Device{}, err
+// merge adds the XAddrs of other, the same device seen on another address
+// or in another answer
+func (device *DiscoveredDevice) merge(other DiscoveredDevice) {
+	for _, xAddr := range other.XAddrs {
+		known := false
+		for _, existing := range device.XAddrs {
+			known = known || existing == xAddr
+		}
+		if !known {
+			device.XAddrs = append(device.XAddrs, xAddr)
+		}
 	}
+}
+
+// discoveryEnvelope holds the parts of the WS-Discovery messages used by the
+// package. Elements are matched by their local name only, so that devices
+// using the 2009 WS-Discovery namespace are understood too.
+type discoveryEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Header  struct {
+		RelatesTo string `xml:"RelatesTo"`
+	} `xml:"Header"`
+	Body struct {
+		Hello          *discoveryEndpoint  `xml:"Hello"`
+		Bye            *discoveryEndpoint  `xml:"Bye"`
+		ProbeMatches   []discoveryEndpoint `xml:"ProbeMatches>ProbeMatch"`
+		ResolveMatches []discoveryEndpoint `xml:"ResolveMatches>ResolveMatch"`
+	} `xml:"Body"`
+}
 
-	// Discover device on interface's network
-	devices, err := discoverDevices(ip.String(), duration)
+// discoveryEndpoint is the description of a device in WS-Discovery messages
+type discoveryEndpoint struct {
+	Address         string `xml:"EndpointReference>Address"`
+	Types           string `xml:"Types"`
+	Scopes          string `xml:"Scopes"`
+	XAddrs          string `xml:"XAddrs"`
+	MetadataVersion string `xml:"MetadataVersion"`
+}
 
-	return devices, nil
+// discovered returns the endpoint as a DiscoveredDevice. An invalid
+// MetadataVersion is left to 0, as it is only informative.
+func (endpoint discoveryEndpoint) discovered() DiscoveredDevice {
+	address := strings.TrimSpace(endpoint.Address)
+	scopes := strings.Fields(endpoint.Scopes)
+	metadataVersion, _ := strconv.ParseUint(strings.TrimSpace(endpoint.MetadataVersion), 10, 32)
+	return DiscoveredDevice{
+		EndpointReference: address,
+		ID:                strings.Replace(address, "urn:uuid:", "", 1),
+		Types:             strings.Fields(endpoint.Types),
+		Scopes:            scopes,
+		ScopeValues:       scopeValues(scopes),
+		XAddrs:            validXAddrs(strings.Fields(endpoint.XAddrs)),
+		MetadataVersion:   uint(metadataVersion),
+	}
+}
+
+// validXAddrs returns the XAddrs which are absolute HTTP or HTTPS URLs, so
+// that garbage sent to the discovery port doesn't end in the results
+func validXAddrs(xAddrs []string) []string {
+	valid := make([]string, 0, len(xAddrs))
+	for _, xAddr := range xAddrs {
+		parsed, err := url.Parse(xAddr)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			DefaultClient.logger().Log(LevelDebug, "ignoring invalid XAddr", "xaddr", xAddr)
+			continue
+		}
+		valid = append(valid, xAddr)
+	}
+	return valid
+}
+
+// discoveredSet gathers discovered devices, merging those with the same
+// EndpointReference, which answered several probes or on several addresses
+type discoveredSet struct {
+	devices []DiscoveredDevice
+	index   map[string]int
+}
+
+// add adds devices to the set
+func (set *discoveredSet) add(devices ...DiscoveredDevice) {
+	if set.index == nil {
+		set.index = make(map[string]int)
+	}
+
+	for _, device := range devices {
+		key := strings.ToLower(device.ID)
+		if key == "" && len(device.XAddrs) > 0 {
+			key = device.XAddrs[0]
+		}
+
+		if i, ok := set.index[key]; ok && key != "" {
+			set.devices[i].merge(device)
+			continue
+		}
+		set.index[key] = len(set.devices)
+		set.devices = append(set.devices, device)
+	}
+}
+
+// list returns the devices of the set, in the order they were first added
+func (set *discoveredSet) list() []DiscoveredDevice {
+	if set.devices == nil {
+		return []DiscoveredDevice{}
+	}
+	return set.devices
+}
+
+// InterfaceError is the failure of the discovery from one local address
+type InterfaceError struct {
+	Interface string
+	Address   string
+	Err       error
+}
+
+// Error implements the error interface
+func (err *InterfaceError) Error() string {
+	return "onvif: discovery on " + err.Interface + " (" + err.Address + "): " + err.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (err *InterfaceError) Unwrap() error {
+	return err.Err
+}
+
+// DiscoveryErrors is returned by Discover, along with the devices found on
+// the other interfaces, when the discovery failed on some of them
+type DiscoveryErrors []*InterfaceError
+
+// Error implements the error interface
+func (errs DiscoveryErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// err returns errs as an error, nil when it is empty
+func (errs DiscoveryErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// devicesOf returns the discovered devices as a list of Device
+func devicesOf(discovered []DiscoveredDevice) []Device {
+	devices := make([]Device, 0, len(discovered))
+	for _, device := range discovered {
+		devices = append(devices, device.Device())
+	}
+	return devices
+}
+
+// StartDiscoveryOn send a WS-Discovery message on the named interface and
+// wait for all matching device to respond. The message is sent to the IPv4
+// group from every IPv4 address of the interface, and to the IPv6
+// link-local group FF02::C when the interface has an IPv6 address.
+func StartDiscoveryOn(interfaceName string, duration time.Duration) ([]Device, error) {
+	devices, err := Discover(interfaceName, duration)
+	return devicesOf(devices), err
 }
 
 // StartDiscovery send a WS-Discovery message and wait for all matching device to respond
 func StartDiscovery(interfaceName string, duration time.Duration) ([]Device, error) {
-	// Get list of interface address
+	devices, err := Discover(interfaceName, duration)
+	return devicesOf(devices), err
+}
+
+// Discover is like StartDiscovery, but returns everything the devices sent
+// about themselves. It probes for NetworkVideoTransmitter devices, like
+// DiscoverMatching with an empty filter. Every ProbeMatch of a response is
+// returned, so that a recorder answering for several channels gives all of
+// them.
+//
+// All the interfaces, or the named one, are probed at once, so that the
+// discovery takes duration whatever their number. A device answering on
+// several interfaces or addresses is returned once, with all its XAddrs.
+// When probing fails on some interfaces, the devices found on the others
+// are returned with DiscoveryErrors. On managed networks, where a discovery
+// proxy answers the Probe with a Hello, the devices are asked from the proxy.
+func Discover(interfaceName string, duration time.Duration) ([]DiscoveredDevice, error) {
+	return DiscoverMatching(interfaceName, duration, ProbeFilter{})
+}
+
+// DiscoverMatching is like Discover, but only the devices selected by filter
+// answer. Devices which answer anyway are dropped when their scopes don't
+// match the filter.
+func DiscoverMatching(interfaceName string, duration time.Duration, filter ProbeFilter) ([]DiscoveredDevice, error) {
+	itfs, err := discoveryInterfaces(interfaceName)
+	if err != nil {
+		return []DiscoveredDevice{}, err
+	}
+
+	return discoverOnInterfaces(itfs, func(localAddress *net.UDPAddr) ([]DiscoveredDevice, error) {
+		return discoverDevices(localAddress, duration, filter)
+	})
+}
+
+// discoveryInterfaces returns the named interface, or the interfaces which
+// can send multicast messages when interfaceName is empty
+func discoveryInterfaces(interfaceName string) ([]net.Interface, error) {
 	if interfaceName != "" {
-		return StartDiscoveryOn(interfaceName, duration)
+		itf, err := net.InterfaceByName(interfaceName) //here your interface
+		if err != nil {
+			return nil, err
+		}
+		return []net.Interface{*itf}, nil
 	}
 
-	addrs, err := net.InterfaceAddrs()
+	all, err := net.Interfaces()
 	if err != nil {
-		return []Device{}, err
+		return nil, err
 	}
 
-	// Fetch IPv4 address
-	var ipAddrs []string
-	for _, addr := range addrs {
-		ipAddr, ok := addr.(*net.IPNet)
-		if ok && !ipAddr.IP.IsLoopback() && ipAddr.IP.To4() != nil {
-			ipAddrs = append(ipAddrs, ipAddr.IP.String())
+	var itfs []net.Interface
+	for _, itf := range all {
+		if itf.Flags&net.FlagUp != 0 && itf.Flags&net.FlagMulticast != 0 && itf.Flags&net.FlagLoopback == 0 {
+			itfs = append(itfs, itf)
 		}
 	}
+	return itfs, nil
+}
 
-	// Create initial discovery results
-	var discoveryResults []Device
+// discoverOnInterfaces calls discover for every discovery address of itfs
+// concurrently and merges the results
+func discoverOnInterfaces(itfs []net.Interface, discover func(localAddress *net.UDPAddr) ([]DiscoveredDevice, error)) ([]DiscoveredDevice, error) {
+	type probe struct {
+		itf          string
+		localAddress *net.UDPAddr
+		devices      []DiscoveredDevice
+		err          error
+	}
 
-	// Discover device on each interface's network
-	for _, ipAddr := range ipAddrs {
-		devices, err := discoverDevices(ipAddr, duration)
-		if err != nil {
-			return []Device{}, err
+	var probes []*probe
+	for _, itf := range itfs {
+		for _, localAddress := range discoveryAddresses(itf) {
+			probes = append(probes, &probe{itf: itf.Name, localAddress: localAddress})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		wg.Add(1)
+		go func(p *probe) {
+			defer wg.Done()
+			p.devices, p.err = discover(p.localAddress)
+		}(p)
+	}
+	wg.Wait()
+
+	// Merge in the order of the interfaces, so that the results are stable
+	var results discoveredSet
+	var errs DiscoveryErrors
+	for _, p := range probes {
+		results.add(p.devices...)
+		if p.err != nil {
+			errs = append(errs, &InterfaceError{
+				Interface: p.itf,
+				Address:   p.localAddress.IP.String(),
+				Err:       p.err,
+			})
+		}
+	}
+
+	return results.list(), errs.err()
+}
+
+// interfaceAddrs returns the addresses of an interface, replaced by the tests
+var interfaceAddrs = func(itf net.Interface) ([]net.Addr, error) {
+	return itf.Addrs()
+}
+
+// discoveryAddresses returns the local addresses to probe from on itf: its
+// IPv4 addresses, then one IPv6 address, link-local if possible, as a single
+// probe reaches the whole link.
+func discoveryAddresses(itf net.Interface) []*net.UDPAddr {
+	addrs, err := interfaceAddrs(itf)
+	if err != nil {
+		return nil
+	}
+
+	var result []*net.UDPAddr
+	var ipv6 *net.UDPAddr
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+
+		if ipNet.IP.To4() != nil {
+			result = append(result, &net.UDPAddr{IP: ipNet.IP})
+		} else if ipv6 == nil || (ipNet.IP.IsLinkLocalUnicast() && !ipv6.IP.IsLinkLocalUnicast()) {
+			ipv6 = &net.UDPAddr{IP: ipNet.IP, Zone: itf.Name}
 		}
+	}
+
+	if ipv6 != nil {
+		result = append(result, ipv6)
+	}
+	return result
+}
+
+// discoveryGroup returns the network and the WS-Discovery group of the IP
+// version of localAddress. The IPv6 group is scoped to the link of the
+// local address.
+func discoveryGroup(localAddress *net.UDPAddr) (string, *net.UDPAddr, error) {
+	network, group := "udp4", discoveryMulticastAddress
+	if localAddress.IP.To4() == nil {
+		network, group = "udp6", "[ff02::c%"+localAddress.Zone+"]:3702"
+	}
+
+	multicastAddress, err := net.ResolveUDPAddr(network, group)
+	return network, multicastAddress, err
+}
 
-		discoveryResults = append(discoveryResults, devices...)
+// discoverDevices sends a Probe from localAddress to the WS-Discovery group
+// of its IP version and reads the answers until duration has elapsed.
+func discoverDevices(localAddress *net.UDPAddr, duration time.Duration, filter ProbeFilter) ([]DiscoveredDevice, error) {
+	network, multicastAddress, err := discoveryGroup(localAddress)
+	if err != nil {
+		return []DiscoveredDevice{}, err
 	}
 
-	return discoveryResults, nil
+	return probeDevices(network, localAddress, []*net.UDPAddr{multicastAddress}, duration, filter)
 }
 
-func discoverDevices(ipAddr string, duration time.Duration) ([]Device, error) {
+// probeDevices sends a Probe for filter from localAddress to addresses and
+// reads the answers until duration has elapsed
+func probeDevices(network string, localAddress *net.UDPAddr, addresses []*net.UDPAddr, duration time.Duration, filter ProbeFilter) ([]DiscoveredDevice, error) {
 	// Create WS-Discovery request
 	requestID := "uuid:" + uuid.New().String()
+	request := discoveryRequest(requestID, "Probe", filter.probeBody())
+
+	devices, err := exchangeDiscovery(network, localAddress, addresses, duration, requestID, request, false)
+	var matching discoveredSet
+	for _, device := range devices {
+		// The network is managed by a discovery proxy, which answered
+		// with a Hello, ask it for the devices when allowed
+		if device.IsDiscoveryProxy() {
+			if filter.FollowProxy == nil {
+				DefaultClient.logger().Log(LevelInfo, "ignoring discovery proxy", "xaddr", device.XAddrs[0])
+				continue
+			}
+			matching.add(probeProxy(*filter.FollowProxy, device, duration, filter)...)
+			continue
+		}
+
+		if !filter.matches(device) {
+			DefaultClient.logger().Log(LevelDebug, "discovered device does not match the probe scopes", "id", device.ID)
+			continue
+		}
+		matching.add(device)
+	}
+
+	return matching.list(), err
+}
+
+// discoveryRequest returns the WS-Discovery message with the given action,
+// e.g. Probe or Resolve, and body
+func discoveryRequest(messageID, action, body string) string {
 	//request := `
 	//	<?xml version="1.0" encoding="UTF-8"?>
 	//	<e:Envelope
@@ -109,149 +516,165 @@ func discoverDevices(ipAddr string, duration time.Duration) ([]Device, error) {
 					xmlns:s="http://www.w3.org/2003/05/soap-envelope"
 					xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing">
 					<s:Header>
-						<a:Action s:mustUnderstand="1">http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</a:Action>
-						<a:MessageID>` + requestID + `</a:MessageID>
+						<a:Action s:mustUnderstand="1">http://schemas.xmlsoap.org/ws/2005/04/discovery/` + action + `</a:Action>
+						<a:MessageID>` + messageID + `</a:MessageID>
 						<a:ReplyTo><a:Address>http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>
 						<a:To s:mustUnderstand="1">urn:schemas-xmlsoap-org:ws:2005:04:discovery</a:To>
 					</s:Header>
-					<s:Body>
-						<Probe xmlns="http://schemas.xmlsoap.org/ws/2005/04/discovery">
-							<d:Types xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dp0="http://www.onvif.org/ver10/network/wsdl">dp0:NetworkVideoTransmitter</d:Types>
-						</Probe>
-					</s:Body>
+					<s:Body>` + body + `</s:Body>
 				</s:Envelope>`
 
 	// Clean WS-Discovery message
 	request = regexp.MustCompile(`>\s+<`).ReplaceAllString(request, "><")
 	request = regexp.MustCompile(`\s+`).ReplaceAllString(request, " ")
+	return request
+}
 
-	// Create UDP address for local and multicast address
-	localAddress, err := net.ResolveUDPAddr("udp4", ipAddr+":0")
-	if err != nil {
-		return []Device{}, err
-	}
-
-	multicastAddress, err := net.ResolveUDPAddr("udp4", "239.255.255.250:3702")
-	if err != nil {
-		return []Device{}, err
+// udpRepeat returns how many times a request sent to addresses is repeated
+func udpRepeat(addresses []*net.UDPAddr) int {
+	if len(addresses) == 1 && addresses[0].IP.IsMulticast() {
+		return multicastUDPRepeat
 	}
+	return unicastUDPRepeat
+}
 
+// exchangeDiscovery sends request from localAddress to addresses and reads
+// the answers to it until duration has elapsed, or until the first device
+// when first is set. The request is repeated as required by SOAP-over-UDP
+// since UDP may lose it.
+func exchangeDiscovery(network string, localAddress *net.UDPAddr, addresses []*net.UDPAddr, duration time.Duration, messageID, request string, first bool) ([]DiscoveredDevice, error) {
 	// Create UDP connection to listen for respond from matching device
-	conn, err := net.ListenUDP("udp", localAddress)
+	conn, err := net.ListenUDP(network, localAddress)
 	if err != nil {
-		return []Device{}, err
+		return []DiscoveredDevice{}, err
 	}
 	defer conn.Close()
 
-	// Set connection's timeout
-	err = conn.SetDeadline(time.Now().Add(duration))
+	// Send WS-Discovery request
+	err = sendDiscovery(conn, addresses, request)
 	if err != nil {
-		return []Device{}, err
-	}
-
-	// Send WS-Discovery request to multicast address
-	_, err = conn.WriteToUDP([]byte(request), multicastAddress)
-	if err != nil {
-		return []Device{}, err
+		return []DiscoveredDevice{}, err
 	}
+	end := time.Now().Add(duration)
+	repeats := udpRepeat(addresses)
+	repeatDelay := udpMinDelay + time.Duration(rand.Int63n(int64(udpMaxDelay-udpMinDelay)))
+	nextRepeat := time.Now().Add(repeatDelay)
 
 	// Create initial discovery results
-	discoveryResults := make([]Device, 0)
+	var discoveryResults discoveredSet
 
 	// Keep reading UDP message until timeout
+	buffer := make([]byte, 64*1024)
 	for {
-		// Create buffer and receive UDP response
-		buffer := make([]byte, 10*1024)
-		_, _, err = conn.ReadFromUDP(buffer)
+		// Wake up for the next repetition of the request
+		deadline := end
+		if repeats > 0 && nextRepeat.Before(end) {
+			deadline = nextRepeat
+		}
+		err = conn.SetReadDeadline(deadline)
+		if err != nil {
+			return discoveryResults.list(), err
+		}
+
+		// Receive UDP response
+		n, _, err := conn.ReadFromUDP(buffer)
 
 		// Check if connection timeout
 		if err != nil {
-			if udpErr, ok := err.(net.Error); ok && udpErr.Timeout() {
+			if udpErr, ok := err.(net.Error); !ok || !udpErr.Timeout() {
+				return discoveryResults.list(), err
+			}
+			if deadline == end {
 				break
-			} else {
-				return discoveryResults, err
 			}
+
+			// Repeat the request, each time after twice the delay
+			err = sendDiscovery(conn, addresses, request)
+			if err != nil {
+				return discoveryResults.list(), err
+			}
+			repeats--
+			repeatDelay *= 2
+			if repeatDelay > udpUpperDelay {
+				repeatDelay = udpUpperDelay
+			}
+			nextRepeat = nextRepeat.Add(repeatDelay)
+			continue
 		}
 
-		//fmt.Println(string(buffer))
-		// Read and parse WS-Discovery response
-		device, err := readDiscoveryResponse(requestID, buffer)
-		if err != nil && err != errWrongDiscoveryResponse {
-			return discoveryResults, err
+		// Read and parse WS-Discovery response. Anything can be received on
+		// the port, such as the answers to other clients or garbage, so
+		// messages which can't be used are skipped.
+		devices, err := readDiscoveryResponse(messageID, buffer[:n])
+		if err != nil {
+			continue
 		}
 
-		// Push device to results
-		discoveryResults = append(discoveryResults, device)
+		// Push devices to results, answers to the repeated request are merged
+		discoveryResults.add(devices...)
+		if first && len(devices) > 0 {
+			break
+		}
 	}
 
-	return discoveryResults, nil
+	return discoveryResults.list(), nil
 }
 
-// readDiscoveryResponse reads and parses WS-Discovery response
-func readDiscoveryResponse(messageID string, buffer []byte) (Device, error) {
-	glog.Infof("Discover response: %s", string(buffer))
+// sendDiscovery sends request to every address. When it is sent to several
+// addresses, as in a sweep, it fails only if no address could be reached.
+func sendDiscovery(conn *net.UDPConn, addresses []*net.UDPAddr, request string) error {
+	var lastErr error
+	sent := false
+	for _, address := range addresses {
+		if _, err := conn.WriteToUDP([]byte(request), address); err != nil {
+			DefaultClient.logger().Log(LevelDebug, "sending discovery request failed", "address", address, "error", err)
+			lastErr = err
+			continue
+		}
+		sent = true
+	}
 
-	// Inital result
-	result := Device{}
+	if !sent {
+		return lastErr
+	}
+	return nil
+}
 
-	// Parse XML to map
-	mapXML, err := mxj.NewMapXml(buffer)
-	if err != nil {
-		glog.Warningf("Parse response error %v", err)
-		return result, err
+// readDiscoveryResponse reads and parses WS-Discovery response, and returns
+// a device for every ProbeMatch or ResolveMatch with an address
+func readDiscoveryResponse(messageID string, buffer []byte) ([]DiscoveredDevice, error) {
+	logger := DefaultClient.logger()
+	logger.Log(LevelDebug, "discovery response", "envelope", string(buffer))
+
+	var envelope discoveryEnvelope
+	if err := xml.Unmarshal(buffer, &envelope); err != nil {
+		logger.Log(LevelDebug, "parsing discovery response failed", "error", err)
+		return nil, err
 	}
 
 	// Check if this response is for our request
-	responseMessageID, err := mapXML.ValueForPath("Envelope.Header.RelatesTo")
-	if err != nil {
-		glog.Warningf("Parse message id error %v", err)
-		return result, err
+	if responseMessageID := strings.TrimSpace(envelope.Header.RelatesTo); responseMessageID != messageID {
+		logger.Log(LevelDebug, "unrelated discovery response", "relatesTo", responseMessageID, "messageID", messageID)
+		return nil, errWrongDiscoveryResponse
 	}
 
-	if responseMessageMap, ok := responseMessageID.(map[string]interface{}); ok {
-		responseMessage := responseMessageMap["#text"].(string)
-		if responseMessage != messageID {
-			glog.Info(responseMessage)
-			glog.Info(messageID)
-			return result, errWrongDiscoveryResponse
-		}
-	} else {
-		if responseMessageID != messageID {
-			glog.Info(responseMessageID)
-			glog.Info(messageID)
-			return result, errWrongDiscoveryResponse
-		}
+	matches := append(envelope.Body.ProbeMatches, envelope.Body.ResolveMatches...)
+	// A discovery proxy suppresses the multicast answers of the devices of
+	// a managed network and announces itself with a Hello instead
+	if hello := envelope.Body.Hello; hello != nil && hello.discovered().IsDiscoveryProxy() {
+		matches = append(matches, *hello)
 	}
-
-	// Get device's ID and clean it
-	deviceID, _ := mapXML.ValueForPathString("Envelope.Body.ProbeMatches.ProbeMatch.EndpointReference.Address")
-	deviceID = strings.Replace(deviceID, "urn:uuid:", "", 1)
-	glog.Infof("Discover device id: %s", deviceID)
-
-	// Get device's name
-	deviceName := ""
-	scopes, _ := mapXML.ValueForPathString("Envelope.Body.ProbeMatches.ProbeMatch.Scopes")
-	for _, scope := range strings.Split(scopes, " ") {
-		if strings.HasPrefix(scope, "onvif://www.onvif.org/name/") {
-			deviceName = strings.Replace(scope, "onvif://www.onvif.org/name/", "", 1)
-			deviceName = strings.Replace(deviceName, "_", " ", -1)
-			break
+	result := make([]DiscoveredDevice, 0, len(matches))
+	for _, match := range matches {
+		device := match.discovered()
+		if len(device.XAddrs) == 0 {
+			logger.Log(LevelWarn, "discovered device has no address", "id", device.ID)
+			continue
 		}
-	}
 
-	// Get device's xAddrs
-	xAddrs, _ := mapXML.ValueForPathString("Envelope.Body.ProbeMatches.ProbeMatch.XAddrs")
-	listXAddr := strings.Split(xAddrs, " ")
-	glog.Infof("Discover address: %s", xAddrs)
-	if len(listXAddr) == 0 {
-		glog.Warning("Discover address len 0")
-		return result, errors.New("Device does not have any xAddr ")
+		logger.Log(LevelDebug, "discovered device", "id", device.ID, "xaddrs", device.XAddrs)
+		result = append(result, device)
 	}
 
-	// Finalize result
-	result.ID = deviceID
-	result.Name = deviceName
-	result.XAddr = listXAddr[0]
-
 	return result, nil
 }
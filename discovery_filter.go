@@ -0,0 +1,165 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+)
+
+// Types of devices which can be probed for
+var (
+	TypeNetworkVideoTransmitter = xml.Name{Space: "http://www.onvif.org/ver10/network/wsdl", Local: "NetworkVideoTransmitter"}
+	TypeNetworkVideoDisplay     = xml.Name{Space: "http://www.onvif.org/ver10/network/wsdl", Local: "NetworkVideoDisplay"}
+	TypeNetworkVideoStorage     = xml.Name{Space: "http://www.onvif.org/ver10/network/wsdl", Local: "NetworkVideoStorage"}
+	TypeNetworkVideoAnalytics   = xml.Name{Space: "http://www.onvif.org/ver10/network/wsdl", Local: "NetworkVideoAnalytics"}
+	// TypeDevice is implemented by every ONVIF device
+	TypeDevice = xml.Name{Space: "http://www.onvif.org/ver10/device/wsdl", Local: "Device"}
+)
+
+// Rules to match the scopes of a Probe with the scopes of the devices
+const (
+	// MatchByRFC3986 matches a scope when it is a prefix of a device
+	// scope, segment by segment, e.g. onvif://www.onvif.org/location/
+	// building-a matches onvif://www.onvif.org/location/building-a/floor-2.
+	// It is the default rule.
+	MatchByRFC3986 = "http://schemas.xmlsoap.org/ws/2005/04/discovery/rfc3986"
+	// MatchByUUID matches scopes which are the same UUID
+	MatchByUUID = "http://schemas.xmlsoap.org/ws/2005/04/discovery/uuid"
+	// MatchByLDAP matches scopes which are the same LDAP name
+	MatchByLDAP = "http://schemas.xmlsoap.org/ws/2005/04/discovery/ldap"
+	// MatchByStrcmp0 matches scopes which are the same string
+	MatchByStrcmp0 = "http://schemas.xmlsoap.org/ws/2005/04/discovery/strcmp0"
+)
+
+// ProbeFilter selects the devices which answer a Probe. A device answers
+// when it implements all the Types and has a scope matching each of the
+// Scopes.
+type ProbeFilter struct {
+	// Types of the devices, TypeNetworkVideoTransmitter when empty
+	Types []xml.Name
+
+	// Scopes the devices must have, e.g. onvif://www.onvif.org/location/
+	// building-a
+	Scopes []string
+
+	// MatchBy is the rule used to match the Scopes, MatchByRFC3986 when
+	// empty
+	MatchBy string
+
+	// FollowProxy, when not nil, is used to ask the discovery proxies
+	// which answer a multicast Probe with a Hello for the devices of their
+	// managed network. Its XAddr is replaced by the address announced by
+	// the proxy, its credentials and Client are used. Proxies are ignored
+	// when it is nil: any host of the network can announce itself as a
+	// proxy, so they should only be followed on trusted networks.
+	FollowProxy *DiscoveryProxy
+}
+
+// probeBody returns the Probe element for the filter
+func (filter ProbeFilter) probeBody() string {
+	types := filter.Types
+	if len(types) == 0 {
+		types = []xml.Name{TypeNetworkVideoTransmitter}
+	}
+
+	// Declare a prefix for every namespace of the types
+	namespaces := make([]string, 0, len(types))
+	qualifiedNames := make([]string, 0, len(types))
+	for _, name := range types {
+		prefix := -1
+		for i, namespace := range namespaces {
+			if namespace == name.Space {
+				prefix = i
+				break
+			}
+		}
+		if prefix < 0 {
+			prefix = len(namespaces)
+			namespaces = append(namespaces, name.Space)
+		}
+		qualifiedNames = append(qualifiedNames, "dp"+intToString(prefix)+":"+name.Local)
+	}
+
+	body := `<Probe xmlns="http://schemas.xmlsoap.org/ws/2005/04/discovery">
+				<d:Types xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery"`
+	for i, namespace := range namespaces {
+		body += ` xmlns:dp` + intToString(i) + `="` + xmlEscape(namespace) + `"`
+	}
+	body += `>` + strings.Join(qualifiedNames, " ") + `</d:Types>`
+
+	if len(filter.Scopes) > 0 {
+		body += `<Scopes`
+		if filter.MatchBy != "" {
+			body += ` MatchBy="` + xmlEscape(filter.MatchBy) + `"`
+		}
+		body += `>` + xmlEscape(strings.Join(filter.Scopes, " ")) + `</Scopes>`
+	}
+
+	return body + `</Probe>`
+}
+
+// matches reports whether the scopes of device match the filter. Some
+// devices answer every Probe whatever its scopes, so they are checked again
+// for the rules which can be checked locally. Types are left to the devices,
+// as many of them only list some of the types they implement.
+func (filter ProbeFilter) matches(device DiscoveredDevice) bool {
+	var match func(scope, deviceScope string) bool
+	switch filter.MatchBy {
+	case "", MatchByRFC3986:
+		match = matchRFC3986
+	case MatchByStrcmp0:
+		match = func(scope, deviceScope string) bool { return scope == deviceScope }
+	default:
+		return true
+	}
+
+	for _, scope := range filter.Scopes {
+		matched := false
+		for _, deviceScope := range device.Scopes {
+			matched = matched || match(scope, deviceScope)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRFC3986 reports whether scope matches deviceScope with the RFC 3986
+// rule of WS-Discovery: the schemes and authorities are the same, ignoring
+// case, and the path of scope is a segment-wise prefix of the device's.
+func matchRFC3986(scope, deviceScope string) bool {
+	scopeURL, err := url.Parse(scope)
+	if err != nil {
+		return scope == deviceScope
+	}
+	deviceURL, err := url.Parse(deviceScope)
+	if err != nil {
+		return false
+	}
+
+	if !strings.EqualFold(scopeURL.Scheme, deviceURL.Scheme) || !strings.EqualFold(scopeURL.Host, deviceURL.Host) {
+		return false
+	}
+
+	segments := pathSegments(scopeURL.Path)
+	deviceSegments := pathSegments(deviceURL.Path)
+	if len(segments) > len(deviceSegments) {
+		return false
+	}
+	for i, segment := range segments {
+		if segment != deviceSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathSegments splits a path, ignoring its leading and trailing slashes
+func pathSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
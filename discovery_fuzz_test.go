@@ -0,0 +1,79 @@
+//go:build go1.18
+// +build go1.18
+
+package onvif
+
+import (
+	"net/url"
+	"testing"
+)
+
+// discoveryCorpus seeds the fuzzers with valid messages and the garbage
+// seen on the discovery port
+var discoveryCorpus = []string{
+	testProbeMatches,
+	testHello,
+	testBye,
+	"",
+	"garbage",
+	`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Header><a:RelatesTo/></s:Header><s:Body/></s:Envelope>`,
+	`<Envelope><Header><RelatesTo>uuid:probe</RelatesTo></Header><Body><ProbeMatches><ProbeMatch><XAddrs> </XAddrs></ProbeMatch></ProbeMatches></Body></Envelope>`,
+	`<Envelope><Header><RelatesTo>uuid:probe</RelatesTo></Header><Body><Hello><Types>DiscoveryProxy</Types><XAddrs>file:///etc/passwd</XAddrs></Hello></Body></Envelope>`,
+	`M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\n\r\n`,
+}
+
+func FuzzReadDiscoveryResponse(f *testing.F) {
+	for _, message := range discoveryCorpus {
+		f.Add(message)
+	}
+
+	f.Fuzz(func(t *testing.T, message string) {
+		devices, err := readDiscoveryResponse("uuid:probe", []byte(message))
+		if err != nil && len(devices) > 0 {
+			t.Errorf("got %d devices with error %v", len(devices), err)
+		}
+		for _, device := range devices {
+			checkDiscoveredDevice(t, device)
+		}
+	})
+}
+
+func FuzzParseAnnouncement(f *testing.F) {
+	for _, message := range discoveryCorpus {
+		f.Add(message)
+	}
+
+	f.Fuzz(func(t *testing.T, message string) {
+		event, ok := parseAnnouncement([]byte(message))
+		if !ok {
+			return
+		}
+		if event.Device.EndpointReference == "" {
+			t.Errorf("announcement without EndpointReference: %+v", event)
+		}
+		for _, xAddr := range event.Device.XAddrs {
+			checkXAddr(t, xAddr)
+		}
+	})
+}
+
+// checkDiscoveredDevice fails when device is not usable
+func checkDiscoveredDevice(t *testing.T, device DiscoveredDevice) {
+	if len(device.XAddrs) == 0 {
+		t.Errorf("device without XAddrs: %+v", device)
+	}
+	for _, xAddr := range device.XAddrs {
+		checkXAddr(t, xAddr)
+	}
+	if device.Device().XAddr == "" {
+		t.Errorf("empty Device for %+v", device)
+	}
+}
+
+// checkXAddr fails when xAddr is not an HTTP URL
+func checkXAddr(t *testing.T, xAddr string) {
+	parsed, err := url.Parse(xAddr)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		t.Errorf("invalid XAddr %q", xAddr)
+	}
+}
@@ -0,0 +1,142 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"net"
+	"strings"
+)
+
+// discoveryMulticastAddress is the IPv4 group and port of WS-Discovery
+const discoveryMulticastAddress = "239.255.255.250:3702"
+
+// DiscoveryEventType tells whether a device announced its arrival or its
+// departure
+type DiscoveryEventType int
+
+const (
+	// DeviceAppeared is sent for a Hello, when a device joins the network
+	DeviceAppeared DiscoveryEventType = iota
+	// DeviceLeft is sent for a Bye, when a device leaves the network
+	DeviceLeft
+)
+
+// String implements the fmt.Stringer interface
+func (eventType DiscoveryEventType) String() string {
+	switch eventType {
+	case DeviceAppeared:
+		return "DeviceAppeared"
+	case DeviceLeft:
+		return "DeviceLeft"
+	}
+	return "DiscoveryEventType(" + intToString(int(eventType)) + ")"
+}
+
+// DiscoveryEvent is a WS-Discovery announcement received by ListenDiscovery
+type DiscoveryEvent struct {
+	Type DiscoveryEventType
+
+	// Device is the announced device. A Bye usually carries only its
+	// EndpointReference.
+	Device DiscoveredDevice
+
+	// From is the address the announcement was sent from
+	From net.Addr
+}
+
+// ListenDiscovery joins the WS-Discovery multicast group on the named
+// interface, or on the system default one when interfaceName is empty, and
+// sends an event on the returned channel for every Hello and Bye announced
+// by a device. Unlike StartDiscovery, it sends no Probe, so devices are seen
+// as they boot or shut down.
+//
+// The channel is closed when ctx is done, or when the socket fails.
+func ListenDiscovery(ctx context.Context, interfaceName string) (<-chan DiscoveryEvent, error) {
+	var itf *net.Interface
+	if interfaceName != "" {
+		var err error
+		itf, err = net.InterfaceByName(interfaceName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", discoveryMulticastAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", itf, group)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan DiscoveryEvent)
+	go listenAnnouncements(ctx, conn, events)
+	return events, nil
+}
+
+// listenAnnouncements reads the announcements received by conn until ctx is
+// done or the connection fails, then closes conn and events.
+func listenAnnouncements(ctx context.Context, conn net.PacketConn, events chan<- DiscoveryEvent) {
+	defer close(events)
+	defer conn.Close()
+
+	// Unblock the read below when ctx is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	logger := DefaultClient.logger()
+	buffer := make([]byte, 64*1024)
+	for {
+		n, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Log(LevelError, "discovery listener stopped", "error", err)
+			}
+			return
+		}
+
+		event, ok := parseAnnouncement(buffer[:n])
+		if !ok {
+			continue
+		}
+		event.From = from
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// parseAnnouncement parses a Hello or a Bye. Other messages sent to the
+// group, such as the Probes of other clients, are ignored.
+func parseAnnouncement(buffer []byte) (DiscoveryEvent, bool) {
+	var envelope discoveryEnvelope
+	if err := xml.Unmarshal(buffer, &envelope); err != nil {
+		DefaultClient.logger().Log(LevelDebug, "ignoring discovery message", "error", err)
+		return DiscoveryEvent{}, false
+	}
+
+	event := DiscoveryEvent{}
+	endpoint := envelope.Body.Hello
+	if endpoint == nil {
+		event.Type = DeviceLeft
+		endpoint = envelope.Body.Bye
+	}
+	if endpoint == nil || strings.TrimSpace(endpoint.Address) == "" {
+		return DiscoveryEvent{}, false
+	}
+
+	event.Device = endpoint.discovered()
+	return event, true
+}
@@ -0,0 +1,117 @@
+package onvif
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testHello = `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl">
+	<s:Header>
+		<a:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Hello</a:Action>
+		<a:MessageID>uuid:5a1b7f4e-0000-0000-0000-000000000001</a:MessageID>
+		<d:AppSequence InstanceId="1" MessageNumber="1"/>
+	</s:Header>
+	<s:Body>
+		<d:Hello>
+			<a:EndpointReference><a:Address>urn:uuid:1419d68a-1dd2-11b2-a105-0123456789ab</a:Address></a:EndpointReference>
+			<d:Types>dn:NetworkVideoTransmitter</d:Types>
+			<d:Scopes>onvif://www.onvif.org/type/video_encoder onvif://www.onvif.org/name/Front_Door</d:Scopes>
+			<d:XAddrs>http://192.0.2.10/onvif/device_service http://[2001:db8::10]/onvif/device_service</d:XAddrs>
+			<d:MetadataVersion>1</d:MetadataVersion>
+		</d:Hello>
+	</s:Body>
+</s:Envelope>`
+
+const testBye = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery">
+	<s:Header><a:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Bye</a:Action></s:Header>
+	<s:Body><d:Bye><a:EndpointReference><a:Address>urn:uuid:1419d68a-1dd2-11b2-a105-0123456789ab</a:Address></a:EndpointReference></d:Bye></s:Body>
+</s:Envelope>`
+
+func TestParseAnnouncement(t *testing.T) {
+	hello, ok := parseAnnouncement([]byte(testHello))
+	if !ok {
+		t.Fatal("Hello was not parsed")
+	}
+	want := DiscoveryEvent{
+		Type: DeviceAppeared,
+		Device: DiscoveredDevice{
+			EndpointReference: "urn:uuid:1419d68a-1dd2-11b2-a105-0123456789ab",
+			ID:                "1419d68a-1dd2-11b2-a105-0123456789ab",
+			Types:             []string{"dn:NetworkVideoTransmitter"},
+			Scopes:            []string{"onvif://www.onvif.org/type/video_encoder", "onvif://www.onvif.org/name/Front_Door"},
+			ScopeValues: map[string][]string{
+				"type": {"video_encoder"},
+				"name": {"Front_Door"},
+			},
+			XAddrs:          []string{"http://192.0.2.10/onvif/device_service", "http://[2001:db8::10]/onvif/device_service"},
+			MetadataVersion: 1,
+		},
+	}
+	if !reflect.DeepEqual(hello, want) {
+		t.Errorf("got %+v, want %+v", hello, want)
+	}
+
+	bye, ok := parseAnnouncement([]byte(testBye))
+	if !ok || bye.Type != DeviceLeft || bye.Device.EndpointReference != want.Device.EndpointReference {
+		t.Errorf("unexpected Bye %+v", bye)
+	}
+
+	for _, message := range []string{
+		"",
+		"not xml",
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><Probe/></s:Body></s:Envelope>`,
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><Hello/></s:Body></s:Envelope>`,
+	} {
+		if event, ok := parseAnnouncement([]byte(message)); ok {
+			t.Errorf("%q parsed as %+v", message, event)
+		}
+	}
+}
+
+func TestListenAnnouncements(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan DiscoveryEvent)
+	go listenAnnouncements(ctx, conn, events)
+
+	sender, err := net.DialUDP("udp4", nil, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	for _, message := range []string{"garbage", testHello, testBye} {
+		if _, err := sender.Write([]byte(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []DiscoveryEventType{DeviceAppeared, DeviceLeft} {
+		select {
+		case event := <-events:
+			if event.Type != want || event.From == nil {
+				t.Errorf("got %v event from %v, want %v", event.Type, event.From, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %v event received", want)
+		}
+	}
+
+	cancel()
+	select {
+	case _, open := <-events:
+		if open {
+			t.Error("unexpected event after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("events not closed after cancel")
+	}
+}
@@ -0,0 +1,117 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiscoveryProxy is a WS-Discovery proxy, which answers the Probe and
+// Resolve messages over HTTP for the devices of a managed network. It finds
+// the devices of sites where multicast is disabled.
+//
+// The multicast discovery functions switch to the proxy when it answers a
+// Probe with a Hello, if ProbeFilter.FollowProxy allows it.
+type DiscoveryProxy struct {
+	// XAddr is the address of the proxy service
+	XAddr    string
+	User     string
+	Password string
+	AuthMode AuthMode
+
+	// Client sends the requests, DefaultClient when nil
+	Client *Client
+}
+
+// Probe asks the proxy for the devices selected by filter
+func (proxy DiscoveryProxy) Probe(filter ProbeFilter) ([]DiscoveredDevice, error) {
+	return proxy.ProbeContext(context.Background(), filter)
+}
+
+// ProbeContext is like Probe but uses ctx for the request.
+func (proxy DiscoveryProxy) ProbeContext(ctx context.Context, filter ProbeFilter) ([]DiscoveredDevice, error) {
+	var response struct {
+		XMLName xml.Name            `xml:"ProbeMatches"`
+		Matches []discoveryEndpoint `xml:"ProbeMatch"`
+	}
+	if err := proxy.soap("Probe", filter.probeBody()).CallContext(ctx, proxy.XAddr, &response); err != nil {
+		return []DiscoveredDevice{}, err
+	}
+
+	var devices discoveredSet
+	for _, match := range response.Matches {
+		device := match.discovered()
+		if len(device.XAddrs) == 0 || !filter.matches(device) {
+			continue
+		}
+		devices.add(device)
+	}
+	return devices.list(), nil
+}
+
+// Resolve asks the proxy for the current XAddrs of the device with the
+// given EndpointReference. ErrNotResolved is returned when the proxy doesn't
+// know the device.
+func (proxy DiscoveryProxy) Resolve(endpointReference string) (DiscoveredDevice, error) {
+	return proxy.ResolveContext(context.Background(), endpointReference)
+}
+
+// ResolveContext is like Resolve but uses ctx for the request.
+func (proxy DiscoveryProxy) ResolveContext(ctx context.Context, endpointReference string) (DiscoveredDevice, error) {
+	var response struct {
+		XMLName xml.Name            `xml:"ResolveMatches"`
+		Matches []discoveryEndpoint `xml:"ResolveMatch"`
+	}
+	if err := proxy.soap("Resolve", resolveBody(endpointReference)).CallContext(ctx, proxy.XAddr, &response); err != nil {
+		return DiscoveredDevice{}, err
+	}
+
+	for _, match := range response.Matches {
+		if device := match.discovered(); len(device.XAddrs) > 0 {
+			return device, nil
+		}
+	}
+	return DiscoveredDevice{}, ErrNotResolved
+}
+
+// soap returns the SOAP request of the WS-Discovery action, addressed to
+// the proxy as required by the managed mode
+func (proxy DiscoveryProxy) soap(action, body string) SOAP {
+	return SOAP{
+		XMLNs: []string{`xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing"`},
+		Headers: []string{
+			`<a:Action s:mustUnderstand="1">http://schemas.xmlsoap.org/ws/2005/04/discovery/` + action + `</a:Action>`,
+			`<a:MessageID>uuid:` + uuid.New().String() + `</a:MessageID>`,
+			`<a:ReplyTo><a:Address>http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>`,
+			`<a:To s:mustUnderstand="1">` + xmlEscape(proxy.XAddr) + `</a:To>`,
+		},
+		Body:     body,
+		User:     proxy.User,
+		Password: proxy.Password,
+		AuthMode: proxy.AuthMode,
+		Client:   proxy.Client,
+	}
+}
+
+// announcedProxy returns proxy addressed to the discovery proxy which
+// announced itself with a Hello
+func announcedProxy(proxy DiscoveryProxy, device DiscoveredDevice) DiscoveryProxy {
+	proxy.XAddr = device.XAddrs[0]
+	return proxy
+}
+
+// probeProxy asks the proxy which answered a multicast Probe for the devices
+// selected by filter, within duration. A failing proxy is logged, as the
+// devices which answered the multicast Probe are still returned.
+func probeProxy(proxy DiscoveryProxy, device DiscoveredDevice, duration time.Duration, filter ProbeFilter) []DiscoveredDevice {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	devices, err := announcedProxy(proxy, device).ProbeContext(ctx, filter)
+	if err != nil {
+		proxy.Client.logger().Log(LevelWarn, "probing discovery proxy failed", "xaddr", device.XAddrs[0], "error", err)
+	}
+	return devices
+}
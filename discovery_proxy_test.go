@@ -0,0 +1,146 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDiscoveryProxy answers every Probe with testProbeMatches and every
+// Resolve with ResolveMatches, checking the managed mode headers
+func fakeDiscoveryProxy(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var request struct {
+			Action string `xml:"Header>Action"`
+			To     string `xml:"Header>To"`
+		}
+		if err := xml.Unmarshal(body, &request); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		if request.To != "http://"+r.Host+r.URL.Path {
+			t.Errorf("request addressed to %q", request.To)
+		}
+
+		answer := testProbeMatches
+		switch request.Action {
+		case "http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe":
+		case "http://schemas.xmlsoap.org/ws/2005/04/discovery/Resolve":
+			answer = strings.Replace(answer, "ProbeMatch", "ResolveMatch", -1)
+		default:
+			t.Errorf("unexpected action %q", request.Action)
+		}
+		w.Write([]byte(answer))
+	}))
+}
+
+func TestDiscoveryProxy(t *testing.T) {
+	server := fakeDiscoveryProxy(t)
+	defer server.Close()
+	proxy := DiscoveryProxy{XAddr: server.URL + "/discovery"}
+
+	devices, err := proxy.Probe(ProbeFilter{Scopes: []string{"onvif://www.onvif.org/name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Errorf("got %d devices, want 2", len(devices))
+	}
+
+	devices, err = proxy.Probe(ProbeFilter{Scopes: []string{"onvif://www.onvif.org/location/building-b"}})
+	if err != nil || len(devices) != 0 {
+		t.Errorf("got %d devices not matching the scopes, error %v", len(devices), err)
+	}
+
+	device, err := proxy.Resolve("urn:uuid:00000000-0000-0000-0000-0000000000c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.ID != "00000000-0000-0000-0000-0000000000c1" {
+		t.Errorf("resolved %q", device.ID)
+	}
+}
+
+func TestProbeRedirectedToDiscoveryProxy(t *testing.T) {
+	server := fakeDiscoveryProxy(t)
+	defer server.Close()
+
+	// The proxy suppresses the multicast Probe with a Hello
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 64*1024)
+		for {
+			n, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+
+			var probe struct {
+				MessageID string `xml:"Header>MessageID"`
+			}
+			xml.Unmarshal(buffer[:n], &probe)
+			hello := strings.Replace(testHello, "<a:MessageID>", "<a:RelatesTo>"+probe.MessageID+"</a:RelatesTo><a:MessageID>", 1)
+			hello = strings.Replace(hello, "dn:NetworkVideoTransmitter", "d:DiscoveryProxy", 1)
+			hello = strings.Replace(hello, "http://192.0.2.10/onvif/device_service", server.URL+"/discovery", 1)
+			conn.WriteToUDP([]byte(hello), from)
+		}
+	}()
+
+	// Proxies are only followed when allowed
+	address := []*net.UDPAddr{conn.LocalAddr().(*net.UDPAddr)}
+	devices, err := probeDevices("udp4", nil, address, 500*time.Millisecond, ProbeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("got %+v from a proxy which isn't followed", devices)
+	}
+	resolved, err := resolveDevice("udp4", nil, address, time.Second, "urn:uuid:00000000-0000-0000-0000-0000000000c1", nil)
+	if err != nil || len(resolved) != 1 || !resolved[0].IsDiscoveryProxy() {
+		t.Errorf("got %+v, %v, want the proxy which isn't followed", resolved, err)
+	}
+
+	// The proxy is asked with the client of the caller
+	var mu sync.Mutex
+	var requests []string
+	client := NewClient()
+	client.Trace = &Trace{RequestDone: func(ctx context.Context, request TraceRequest, response TraceResponse) {
+		mu.Lock()
+		requests = append(requests, request.XAddr)
+		mu.Unlock()
+	}}
+	proxy := &DiscoveryProxy{Client: client}
+
+	devices, err = probeDevices("udp4", nil, address, 500*time.Millisecond, ProbeFilter{FollowProxy: proxy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 || devices[0].IsDiscoveryProxy() {
+		t.Errorf("got %+v, want the 2 devices of the proxy", devices)
+	}
+
+	resolved, err = resolveDevice("udp4", nil, address, time.Second, "urn:uuid:00000000-0000-0000-0000-0000000000c1", proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].ID != "00000000-0000-0000-0000-0000000000c1" {
+		t.Errorf("unexpected resolve answer %+v", resolved)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 || requests[0] != server.URL+"/discovery" {
+		t.Errorf("proxy asked at %q with the client of the caller", requests)
+	}
+}
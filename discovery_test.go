@@ -0,0 +1,433 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testProbeMatches = `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl" xmlns:tds="http://www.onvif.org/ver10/device/wsdl">
+	<s:Header>
+		<a:MessageID>uuid:8a0c4e62-0000-0000-0000-000000000002</a:MessageID>
+		<a:RelatesTo>uuid:probe</a:RelatesTo>
+		<a:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/ProbeMatches</a:Action>
+	</s:Header>
+	<s:Body>
+		<d:ProbeMatches>
+			<d:ProbeMatch>
+				<a:EndpointReference><a:Address>urn:uuid:00000000-0000-0000-0000-0000000000c1</a:Address></a:EndpointReference>
+				<d:Types>dn:NetworkVideoTransmitter tds:Device</d:Types>
+				<d:Scopes>onvif://www.onvif.org/Profile/Streaming onvif://www.onvif.org/Profile/T onvif://www.onvif.org/name/NVR_Channel%201 onvif://www.onvif.org/hardware/NVR-16 onvif://www.onvif.org/location/city/Hanoi onvif://www.onvif.org/location/building-a http://example.com/custom</d:Scopes>
+				<d:XAddrs>http://192.0.2.20:8000/onvif/device_service https://192.0.2.20/onvif/device_service</d:XAddrs>
+				<d:MetadataVersion>7</d:MetadataVersion>
+			</d:ProbeMatch>
+			<d:ProbeMatch>
+				<a:EndpointReference><a:Address>urn:uuid:00000000-0000-0000-0000-0000000000c2</a:Address></a:EndpointReference>
+				<d:Types>dn:NetworkVideoTransmitter</d:Types>
+				<d:Scopes>onvif://www.onvif.org/name/Channel_2</d:Scopes>
+				<d:XAddrs>http://192.0.2.20:8001/onvif/device_service</d:XAddrs>
+				<d:MetadataVersion>bogus</d:MetadataVersion>
+			</d:ProbeMatch>
+			<d:ProbeMatch>
+				<a:EndpointReference><a:Address>urn:uuid:00000000-0000-0000-0000-0000000000c3</a:Address></a:EndpointReference>
+			</d:ProbeMatch>
+		</d:ProbeMatches>
+	</s:Body>
+</s:Envelope>`
+
+func TestReadDiscoveryResponse(t *testing.T) {
+	devices, err := readDiscoveryResponse("uuid:probe", []byte(testProbeMatches))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2 with an address", len(devices))
+	}
+
+	first := devices[0]
+	if first.ID != "00000000-0000-0000-0000-0000000000c1" || first.MetadataVersion != 7 {
+		t.Errorf("unexpected identity %q or version %d", first.ID, first.MetadataVersion)
+	}
+	if want := []string{"dn:NetworkVideoTransmitter", "tds:Device"}; !reflect.DeepEqual(first.Types, want) {
+		t.Errorf("types %q, want %q", first.Types, want)
+	}
+	if len(first.XAddrs) != 2 || first.XAddrs[1] != "https://192.0.2.20/onvif/device_service" {
+		t.Errorf("xaddrs %q", first.XAddrs)
+	}
+	if first.Name() != "NVR Channel 1" || first.Hardware() != "NVR-16" || first.Location() != "city/Hanoi" {
+		t.Errorf("unexpected name %q, hardware %q or location %q", first.Name(), first.Hardware(), first.Location())
+	}
+	if want := []string{"city/Hanoi", "building-a"}; !reflect.DeepEqual(first.ScopeValues["location"], want) {
+		t.Errorf("locations %q, want %q", first.ScopeValues["location"], want)
+	}
+	if want := []string{"Streaming", "T"}; !reflect.DeepEqual(first.Profiles(), want) {
+		t.Errorf("profiles %q, want %q", first.Profiles(), want)
+	}
+	if len(first.Scopes) != 7 {
+		t.Errorf("scopes %q, want all 7", first.Scopes)
+	}
+
+	second := devices[1]
+	if second.Device() != (Device{ID: "00000000-0000-0000-0000-0000000000c2", Name: "Channel 2", XAddr: "http://192.0.2.20:8001/onvif/device_service"}) {
+		t.Errorf("unexpected device %+v", second.Device())
+	}
+
+	if _, err := readDiscoveryResponse("uuid:other", []byte(testProbeMatches)); err != errWrongDiscoveryResponse {
+		t.Errorf("got %v for another probe, want %v", err, errWrongDiscoveryResponse)
+	}
+}
+
+func TestDiscoveryIgnoresGarbage(t *testing.T) {
+	answer := strings.Replace(testProbeMatches, "http://192.0.2.20:8001/onvif/device_service", "javascript:alert(1) http:// /onvif", 1)
+	devices, err := readDiscoveryResponse("uuid:probe", []byte(answer))
+	if err != nil || len(devices) != 1 {
+		t.Errorf("got %d devices with error %v, want only the device with a valid XAddr", len(devices), err)
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 64*1024)
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+
+		var probe struct {
+			MessageID string `xml:"Header>MessageID"`
+		}
+		xml.Unmarshal(buffer[:n], &probe)
+		for _, message := range []string{"garbage", "<Envelope><Header><RelatesTo/>", testHello} {
+			conn.WriteToUDP([]byte(message), from)
+		}
+		conn.WriteToUDP([]byte(strings.Replace(testProbeMatches, "uuid:probe", probe.MessageID, 1)), from)
+	}()
+
+	devices, err = probeDevices("udp4", nil, []*net.UDPAddr{conn.LocalAddr().(*net.UDPAddr)}, 300*time.Millisecond, ProbeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Errorf("got %d devices after garbage, want 2", len(devices))
+	}
+}
+
+func TestDiscoveredDeviceMerge(t *testing.T) {
+	device := DiscoveredDevice{XAddrs: []string{"http://192.0.2.10/onvif/device_service"}}
+	device.merge(DiscoveredDevice{XAddrs: []string{"http://[fe80::10]/onvif/device_service", "http://192.0.2.10/onvif/device_service"}})
+
+	want := []string{"http://192.0.2.10/onvif/device_service", "http://[fe80::10]/onvif/device_service"}
+	if !reflect.DeepEqual(device.XAddrs, want) {
+		t.Errorf("xaddrs %q, want %q", device.XAddrs, want)
+	}
+}
+
+// fakeDiscoveryDevice answers every Probe it receives with ProbeMatches, and
+// every Resolve with ResolveMatches
+func fakeDiscoveryDevice(t *testing.T, probes chan<- string) *net.UDPConn {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip(err)
+	}
+
+	go func() {
+		buffer := make([]byte, 64*1024)
+		for {
+			n, from, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+
+			var probe struct {
+				MessageID string `xml:"Header>MessageID"`
+				Resolve   *struct {
+					Address string `xml:"EndpointReference>Address"`
+				} `xml:"Body>Resolve"`
+			}
+			xml.Unmarshal(buffer[:n], &probe)
+			probes <- probe.MessageID
+
+			answer := strings.Replace(testProbeMatches, "uuid:probe", probe.MessageID, 1)
+			if probe.Resolve != nil {
+				answer = strings.Replace(answer, "ProbeMatch", "ResolveMatch", -1)
+			}
+			conn.WriteToUDP([]byte(answer), from)
+		}
+	}()
+	return conn
+}
+
+func TestProbeDevicesRepeatsAndMerges(t *testing.T) {
+	probes := make(chan string, 10)
+	device := fakeDiscoveryDevice(t, probes)
+	defer device.Close()
+
+	localAddress := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	devices, err := probeDevices("udp4", localAddress, []*net.UDPAddr{device.LocalAddr().(*net.UDPAddr)}, time.Second, ProbeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(probes) != 1+unicastUDPRepeat {
+		t.Errorf("got %d probes, want %d", len(probes), 1+unicastUDPRepeat)
+	}
+	if first, second := <-probes, <-probes; first != second {
+		t.Errorf("repeated probe has message id %q, want %q", second, first)
+	}
+	if len(devices) != 2 {
+		t.Errorf("got %d devices, want each of the 2 channels once", len(devices))
+	}
+}
+
+func TestUDPRepeat(t *testing.T) {
+	multicast := []*net.UDPAddr{{IP: net.IPv4(239, 255, 255, 250), Port: 3702}}
+	if repeat := udpRepeat(multicast); repeat != 2 {
+		t.Errorf("multicast probes repeated %d times, want 2", repeat)
+	}
+	unicast := []*net.UDPAddr{{IP: net.IPv4(192, 168, 1, 10), Port: 3702}}
+	if repeat := udpRepeat(unicast); repeat != 1 {
+		t.Errorf("unicast probes repeated %d times, want 1", repeat)
+	}
+}
+
+func TestDiscoveryAddresses(t *testing.T) {
+	defer func(addrs func(net.Interface) ([]net.Addr, error)) { interfaceAddrs = addrs }(interfaceAddrs)
+
+	for _, test := range []struct {
+		name  string
+		addrs []string
+		want  []string
+	}{
+		{"ipv4 only", []string{"192.0.2.1/24", "198.51.100.1/24"}, []string{"192.0.2.1", "198.51.100.1"}},
+		{"link-local preferred", []string{"2001:db8::1/64", "192.0.2.1/24", "fe80::1/64"}, []string{"192.0.2.1", "fe80::1%eth0"}},
+		{"first link-local", []string{"fe80::1/64", "fe80::2/64", "2001:db8::1/64"}, []string{"fe80::1%eth0"}},
+		{"global without link-local", []string{"2001:db8::1/64", "2001:db8::2/64"}, []string{"2001:db8::1%eth0"}},
+		{"loopback skipped", []string{"127.0.0.1/8", "::1/128"}, nil},
+	} {
+		var addrs []net.Addr
+		for _, addr := range test.addrs {
+			ip, ipNet, _ := net.ParseCIDR(addr)
+			ipNet.IP = ip
+			addrs = append(addrs, ipNet)
+		}
+		interfaceAddrs = func(net.Interface) ([]net.Addr, error) { return addrs, nil }
+
+		var got []string
+		for _, localAddress := range discoveryAddresses(net.Interface{Name: "eth0"}) {
+			address := localAddress.IP.String()
+			if localAddress.Zone != "" {
+				address += "%" + localAddress.Zone
+			}
+			got = append(got, address)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiscoveryGroup(t *testing.T) {
+	for _, test := range []struct {
+		localAddress *net.UDPAddr
+		network      string
+		group        string
+	}{
+		{&net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, "udp4", "239.255.255.250:3702"},
+		{&net.UDPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, "udp6", "[ff02::c%eth0]:3702"},
+		{&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Zone: "wlan1"}, "udp6", "[ff02::c%wlan1]:3702"},
+	} {
+		network, group, err := discoveryGroup(test.localAddress)
+		if err != nil {
+			t.Errorf("%v: %v", test.localAddress, err)
+			continue
+		}
+		if network != test.network || group.String() != test.group {
+			t.Errorf("%v: got %s %v, want %s %s", test.localAddress, network, group, test.network, test.group)
+		}
+	}
+}
+
+func TestDiscoverOnInterfacesMerges(t *testing.T) {
+	defer func(addrs func(net.Interface) ([]net.Addr, error)) { interfaceAddrs = addrs }(interfaceAddrs)
+	interfaceAddrs = func(net.Interface) ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+			&net.IPNet{IP: net.ParseIP("192.0.2.1").To4(), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	}
+
+	// The devices answering on both IP versions are listed once
+	answers := map[string][]DiscoveredDevice{
+		"udp4": {
+			{ID: "00000000-0000-0000-0000-0000000000c1", XAddrs: []string{"http://192.0.2.20/onvif/device_service"}},
+			{ID: "00000000-0000-0000-0000-0000000000c2", XAddrs: []string{"http://192.0.2.21/onvif/device_service"}},
+		},
+		"udp6": {
+			{ID: "00000000-0000-0000-0000-0000000000C1", XAddrs: []string{"http://[fe80::20]/onvif/device_service"}},
+			{ID: "00000000-0000-0000-0000-0000000000c3", XAddrs: []string{"http://[fe80::22]/onvif/device_service"}},
+		},
+	}
+	devices, err := discoverOnInterfaces([]net.Interface{{Name: "eth0"}}, func(localAddress *net.UDPAddr) ([]DiscoveredDevice, error) {
+		network, _, err := discoveryGroup(localAddress)
+		return answers[network], err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		id     string
+		xAddrs []string
+	}{
+		{"00000000-0000-0000-0000-0000000000c1", []string{"http://192.0.2.20/onvif/device_service", "http://[fe80::20]/onvif/device_service"}},
+		{"00000000-0000-0000-0000-0000000000c2", []string{"http://192.0.2.21/onvif/device_service"}},
+		{"00000000-0000-0000-0000-0000000000c3", []string{"http://[fe80::22]/onvif/device_service"}},
+	} {
+		found := false
+		for _, device := range devices {
+			if device.ID == test.id {
+				found = true
+				if !reflect.DeepEqual(device.XAddrs, test.xAddrs) {
+					t.Errorf("%s: xaddrs %q, want %q", test.id, device.XAddrs, test.xAddrs)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s not discovered", test.id)
+		}
+	}
+	if len(devices) != 3 {
+		t.Errorf("got %d devices, want 3", len(devices))
+	}
+}
+
+func TestDiscoveryErrors(t *testing.T) {
+	cause := errors.New("network is unreachable")
+	var err error = DiscoveryErrors{{Interface: "eth1", Address: "fe80::1", Err: cause}}.err()
+
+	errs, ok := err.(DiscoveryErrors)
+	if !ok || len(errs) != 1 || !errors.Is(errs[0], cause) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := err.Error(); got != "onvif: discovery on eth1 (fe80::1): network is unreachable" {
+		t.Errorf("message %q", got)
+	}
+	if DiscoveryErrors(nil).err() != nil {
+		t.Error("empty DiscoveryErrors is not a nil error")
+	}
+}
+
+func TestProbeFilter(t *testing.T) {
+	filter := ProbeFilter{
+		Types:  []xml.Name{TypeNetworkVideoTransmitter, TypeDevice, TypeNetworkVideoStorage},
+		Scopes: []string{"onvif://www.onvif.org/location/building-a"},
+	}
+
+	var probe struct {
+		Types  string `xml:"Types"`
+		Scopes string `xml:"Scopes"`
+	}
+	if err := xml.Unmarshal([]byte(filter.probeBody()), &probe); err != nil {
+		t.Fatal(err)
+	}
+	if probe.Types != "dp0:NetworkVideoTransmitter dp1:Device dp0:NetworkVideoStorage" || probe.Scopes != filter.Scopes[0] {
+		t.Errorf("unexpected probe %s", filter.probeBody())
+	}
+
+	for _, test := range []struct {
+		scope, deviceScope string
+		match              bool
+	}{
+		{"onvif://www.onvif.org/location/building-a", "onvif://www.onvif.org/location/building-a", true},
+		{"onvif://www.onvif.org/location/building-a", "ONVIF://WWW.ONVIF.ORG/location/building-a/floor-2", true},
+		{"onvif://www.onvif.org/location/building-a/", "onvif://www.onvif.org/location/building-a", true},
+		{"onvif://www.onvif.org/location/building-a", "onvif://www.onvif.org/location/building-ab", false},
+		{"onvif://www.onvif.org/location/building-a", "onvif://www.onvif.org/location", false},
+		{"onvif://www.onvif.org/location/building-a", "http://www.onvif.org/location/building-a", false},
+	} {
+		if got := matchRFC3986(test.scope, test.deviceScope); got != test.match {
+			t.Errorf("matchRFC3986(%q, %q) = %v", test.scope, test.deviceScope, got)
+		}
+	}
+
+	probes := make(chan string, 10)
+	device := fakeDiscoveryDevice(t, probes)
+	defer device.Close()
+
+	localAddress := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	devices, err := probeDevices("udp4", localAddress, []*net.UDPAddr{device.LocalAddr().(*net.UDPAddr)}, 300*time.Millisecond, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].Location() != "city/Hanoi" {
+		t.Errorf("got %+v, want only the device in building-a", devices)
+	}
+}
+
+func TestProbeHostAndResolve(t *testing.T) {
+	probes := make(chan string, 10)
+	device := fakeDiscoveryDevice(t, probes)
+	defer device.Close()
+
+	devices, err := ProbeHost(device.LocalAddr().String(), 300*time.Millisecond, ProbeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Errorf("got %d devices from unicast probe, want 2", len(devices))
+	}
+
+	start := time.Now()
+	resolved, err := resolveDevice("udp4", nil, []*net.UDPAddr{device.LocalAddr().(*net.UDPAddr)}, 5*time.Second, "urn:uuid:00000000-0000-0000-0000-0000000000c1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) == 0 || resolved[0].XAddrs[0] != "http://192.0.2.20:8000/onvif/device_service" {
+		t.Errorf("unexpected resolve answer %+v", resolved)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("resolve waited %v after the answer", elapsed)
+	}
+}
+
+func TestSweepAddresses(t *testing.T) {
+	for _, test := range []struct {
+		cidr  string
+		first string
+		last  string
+		count int
+	}{
+		{"192.0.2.0/30", "192.0.2.1", "192.0.2.2", 2},
+		{"192.0.2.7/32", "192.0.2.7", "192.0.2.7", 1},
+		{"10.1.0.0/23", "10.1.0.1", "10.1.1.254", 510},
+		{"2001:db8::/126", "2001:db8::", "2001:db8::3", 4},
+	} {
+		addresses, err := sweepAddresses(test.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addresses) != test.count || addresses[0].IP.String() != test.first || addresses[len(addresses)-1].IP.String() != test.last {
+			t.Errorf("%s: got %d addresses from %v to %v", test.cidr, len(addresses), addresses[0], addresses[len(addresses)-1])
+		}
+		if addresses[0].Port != discoveryPort {
+			t.Errorf("%s: port %d", test.cidr, addresses[0].Port)
+		}
+	}
+
+	for _, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.0.0/21"} {
+		if _, err := sweepAddresses(cidr); err == nil {
+			t.Errorf("%s: expected error for a range too large", cidr)
+		}
+	}
+	if addresses, err := sweepAddresses("10.1.0.0/22"); err != nil || len(addresses) != 1022 {
+		t.Errorf("10.1.0.0/22: got %d addresses, %v", len(addresses), err)
+	}
+}
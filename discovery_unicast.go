@@ -0,0 +1,175 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// discoveryPort is the UDP port of WS-Discovery
+const discoveryPort = 3702
+
+// maxSweepHostBits limits the size of the ranges swept by ProbeNetwork. The
+// probes of a sweep are sent at once, and repeated, so larger ranges would
+// flood the network segment.
+const maxSweepHostBits = 10
+
+// ErrNotResolved is returned by Resolve when no device answered
+var ErrNotResolved = errors.New("onvif: no device answered the resolve")
+
+// ProbeHost sends a Probe for filter by unicast to host, an IP address with
+// an optional port, 3702 by default. It reaches devices behind routers,
+// where multicast messages are not forwarded.
+func ProbeHost(host string, duration time.Duration, filter ProbeFilter) ([]DiscoveredDevice, error) {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(discoveryPort))
+	}
+
+	address, err := net.ResolveUDPAddr("udp", host)
+	if err != nil {
+		return []DiscoveredDevice{}, err
+	}
+
+	return probeDevices(unicastNetwork(address.IP), nil, []*net.UDPAddr{address}, duration, filter)
+}
+
+// ProbeNetwork sends a Probe for filter by unicast to every host of the
+// CIDR range, e.g. 10.1.2.0/24, and returns the devices which answered
+// within duration. Ranges of more than 1024 addresses, larger than a /22,
+// are refused: larger networks should be swept in parts.
+func ProbeNetwork(cidr string, duration time.Duration, filter ProbeFilter) ([]DiscoveredDevice, error) {
+	addresses, err := sweepAddresses(cidr)
+	if err != nil {
+		return []DiscoveredDevice{}, err
+	}
+
+	return probeDevices(unicastNetwork(addresses[0].IP), nil, addresses, duration, filter)
+}
+
+// Resolve sends a WS-Discovery Resolve on the named interface, or on every
+// interface when interfaceName is empty, to find the current XAddrs of the
+// device with the given EndpointReference, e.g. after its address was
+// changed by DHCP. A bare UUID is accepted as an urn:uuid: reference.
+// ErrNotResolved is returned when no device answered within duration.
+// Discovery proxies answering the Resolve are ignored.
+func Resolve(interfaceName, endpointReference string, duration time.Duration) (DiscoveredDevice, error) {
+	return resolve(interfaceName, endpointReference, duration, nil)
+}
+
+// ResolveFollowingProxy is like Resolve, but when a discovery proxy answers
+// the Resolve with a Hello, it is asked for the device with the credentials
+// and Client of proxy, as ProbeFilter.FollowProxy does for the probes.
+func ResolveFollowingProxy(interfaceName, endpointReference string, duration time.Duration, proxy DiscoveryProxy) (DiscoveredDevice, error) {
+	return resolve(interfaceName, endpointReference, duration, &proxy)
+}
+
+// resolve implements Resolve, following the discovery proxies with proxy
+// when it is not nil
+func resolve(interfaceName, endpointReference string, duration time.Duration, proxy *DiscoveryProxy) (DiscoveredDevice, error) {
+	if !strings.Contains(endpointReference, ":") {
+		endpointReference = "urn:uuid:" + endpointReference
+	}
+
+	itfs, err := discoveryInterfaces(interfaceName)
+	if err != nil {
+		return DiscoveredDevice{}, err
+	}
+
+	devices, err := discoverOnInterfaces(itfs, func(localAddress *net.UDPAddr) ([]DiscoveredDevice, error) {
+		network, multicastAddress, err := discoveryGroup(localAddress)
+		if err != nil {
+			return []DiscoveredDevice{}, err
+		}
+		return resolveDevice(network, localAddress, []*net.UDPAddr{multicastAddress}, duration, endpointReference, proxy)
+	})
+	for _, device := range devices {
+		if strings.EqualFold(device.EndpointReference, endpointReference) {
+			return device, nil
+		}
+	}
+
+	if err == nil {
+		err = ErrNotResolved
+	}
+	return DiscoveredDevice{}, err
+}
+
+// resolveDevice sends a Resolve for endpointReference from localAddress to
+// addresses and returns the first answer. A discovery proxy answering
+// instead of the device is asked with proxy, or returned when it is nil.
+func resolveDevice(network string, localAddress *net.UDPAddr, addresses []*net.UDPAddr, duration time.Duration, endpointReference string, proxy *DiscoveryProxy) ([]DiscoveredDevice, error) {
+	requestID := "uuid:" + uuid.New().String()
+	request := discoveryRequest(requestID, "Resolve", resolveBody(endpointReference))
+
+	devices, err := exchangeDiscovery(network, localAddress, addresses, duration, requestID, request, true)
+	if err != nil || len(devices) == 0 || !devices[0].IsDiscoveryProxy() || proxy == nil {
+		return devices, err
+	}
+
+	// A discovery proxy answered instead of the device, ask it
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	device, err := announcedProxy(*proxy, devices[0]).ResolveContext(ctx, endpointReference)
+	if err != nil {
+		return []DiscoveredDevice{}, err
+	}
+	return []DiscoveredDevice{device}, nil
+}
+
+// resolveBody returns the Resolve element for endpointReference. The a:
+// prefix must be bound to WS-Addressing.
+func resolveBody(endpointReference string) string {
+	return `<Resolve xmlns="http://schemas.xmlsoap.org/ws/2005/04/discovery">
+				<a:EndpointReference><a:Address>` + xmlEscape(endpointReference) + `</a:Address></a:EndpointReference>
+			</Resolve>`
+}
+
+// unicastNetwork returns the UDP network of ip
+func unicastNetwork(ip net.IP) string {
+	if ip.To4() != nil {
+		return "udp4"
+	}
+	return "udp6"
+}
+
+// sweepAddresses returns the discovery address of every host of the CIDR
+// range. The network and broadcast addresses of IPv4 ranges are skipped.
+func sweepAddresses(cidr string) ([]*net.UDPAddr, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	hostBits := bits - ones
+	if hostBits > maxSweepHostBits {
+		return nil, errors.New("onvif: range " + cidr + " is too large to sweep")
+	}
+
+	base := ipNet.IP
+	if ip4 := base.To4(); ip4 != nil {
+		base = ip4
+	}
+	count := 1 << uint(hostBits)
+	first, last := 0, count-1
+	if len(base) == net.IPv4len && hostBits >= 2 {
+		first, last = 1, count-2
+	}
+
+	addresses := make([]*net.UDPAddr, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		ip := append(net.IP(nil), base...)
+		for j, carry := len(ip)-1, i; j >= 0 && carry > 0; j-- {
+			sum := int(ip[j]) + carry
+			ip[j] = byte(sum)
+			carry = sum >> 8
+		}
+		addresses = append(addresses, &net.UDPAddr{IP: ip, Port: discoveryPort})
+	}
+	return addresses, nil
+}
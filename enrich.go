@@ -0,0 +1,203 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultEnrichWorkers is the number of devices enriched at once when
+// Enricher.Workers is not set
+const defaultEnrichWorkers = 4
+
+// errNoXAddr is the error of discovered devices without any XAddr
+var errNoXAddr = errors.New("onvif: discovered device has no XAddr")
+
+// Credentials are a user and a password to try on the discovered devices
+type Credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Enricher reads from discovered devices what WS-Discovery doesn't tell,
+// like GetMediaInformation does for a single device: their information, the
+// XAddrs of their services, their profiles and stream URIs.
+type Enricher struct {
+	// Credentials are tried in order on every device until one is
+	// accepted. Devices are queried without credentials when it is empty.
+	Credentials []Credentials
+
+	// MaxAttempts, Backoff and AuthMode limit the attempts to find the
+	// credentials of a device, as in CredentialChecker, so that devices
+	// don't lock their accounts
+	MaxAttempts int
+	Backoff     time.Duration
+	AuthMode    AuthMode
+
+	// Workers is the number of devices enriched concurrently, 4 when zero
+	Workers int
+
+	// StreamProtocol is the protocol of the stream URIs, RTSP when empty
+	StreamProtocol string
+
+	// Client sends the requests, DefaultClient when nil
+	Client *Client
+}
+
+// EnrichedDevice is a discovered device with the details read from it
+type EnrichedDevice struct {
+	Discovered DiscoveredDevice
+
+	// Device is the device service which answered, with the accepted
+	// credentials
+	Device Device
+
+	Information DeviceInformation
+
+	// Capabilities hold the XAddrs of the services of the device
+	Capabilities DeviceCapabilities
+
+	Profiles []MediaProfile
+
+	// Streams has a stream for every profile whose URI could be read
+	Streams []Stream
+
+	// Err is the first failure met while enriching the device. The details
+	// read before it, and the streams of the other profiles, are kept.
+	Err error
+}
+
+// Enrich reads the details of every device, Workers devices at a time, and
+// returns them in the order of devices. Errors are reported per device.
+func (enricher Enricher) Enrich(devices []DiscoveredDevice) []EnrichedDevice {
+	return enricher.EnrichContext(context.Background(), devices)
+}
+
+// EnrichContext is like Enrich but uses ctx for the requests.
+func (enricher Enricher) EnrichContext(ctx context.Context, devices []DiscoveredDevice) []EnrichedDevice {
+	results := make([]EnrichedDevice, len(devices))
+	forEachDevice(enricher.Workers, len(devices), func(index int) {
+		results[index] = enricher.enrich(ctx, devices[index])
+	})
+	return results
+}
+
+// forEachDevice calls do with the index of every device, from at most
+// workers goroutines at once, defaultEnrichWorkers when it is not set
+func forEachDevice(workers, count int, do func(index int)) {
+	if workers <= 0 {
+		workers = defaultEnrichWorkers
+	}
+	if workers > count {
+		workers = count
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				do(index)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// enrich reads the details of a single device
+func (enricher Enricher) enrich(ctx context.Context, discovered DiscoveredDevice) EnrichedDevice {
+	result := EnrichedDevice{Discovered: discovered}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+
+	device, information, err := enricher.authenticate(ctx, discovered)
+	result.Device = device
+	if err != nil {
+		result.Err = fmt.Errorf("onvif: getting device information: %w", err)
+		return result
+	}
+	result.Information = information
+
+	capabilities, err := device.GetCapabilitiesContext(ctx)
+	if err != nil {
+		result.Err = fmt.Errorf("onvif: getting capabilities: %w", err)
+		return result
+	}
+	result.Capabilities = capabilities
+
+	// Get media profiles
+	media := device
+	if capabilities.Media.XAddr != "" {
+		media.XAddr = capabilities.Media.XAddr
+	}
+	profiles, err := media.GetProfilesContext(ctx)
+	if err != nil {
+		result.Err = fmt.Errorf("onvif: getting profiles: %w", err)
+		return result
+	}
+	result.Profiles = profiles
+
+	protocol := enricher.StreamProtocol
+	if protocol == "" {
+		protocol = "RTSP"
+	}
+	for _, profile := range profiles {
+		uri, err := media.GetStreamURIContext(ctx, profile.Token, protocol)
+		if err != nil {
+			device.logger().Log(LevelWarn, "get stream uri failed", "xaddr", media.XAddr, "token", profile.Token, "error", err)
+			if result.Err == nil {
+				result.Err = fmt.Errorf("onvif: getting stream URI of %s: %w", profile.Token, err)
+			}
+			continue
+		}
+
+		result.Streams = append(result.Streams, Stream{
+			ProfileToken: profile.Token,
+			StreamURI:    uri.URI,
+			Resolution: Resolution{
+				Width:  profile.VideoEncoderConfig.Resolution.Width,
+				Height: profile.VideoEncoderConfig.Resolution.Height,
+			},
+			VideoEncToken:    profile.VideoEncoderConfig.Token,
+			VideoCodec:       profile.VideoEncoderConfig.Encoding,
+			VideoSourceToken: profile.VideoSourceConfig.Token,
+		})
+	}
+
+	return result
+}
+
+// authenticate finds the XAddr and the credentials with which the device
+// answers GetDeviceInformation, with the attempt limit and the backoff of
+// CredentialChecker.
+func (enricher Enricher) authenticate(ctx context.Context, discovered DiscoveredDevice) (Device, DeviceInformation, error) {
+	checker := CredentialChecker{
+		Credentials: enricher.Credentials,
+		MaxAttempts: enricher.MaxAttempts,
+		Backoff:     enricher.Backoff,
+		AuthMode:    enricher.AuthMode,
+		Client:      enricher.Client,
+	}
+	if len(checker.Credentials) == 0 {
+		checker.Credentials = []Credentials{{}}
+	}
+
+	report := checker.check(ctx, discovered)
+	if !report.Authorized {
+		device := discovered.Device()
+		device.Client = enricher.Client
+		return device, DeviceInformation{}, report.Err
+	}
+	return report.Device, report.Information, nil
+}
@@ -0,0 +1,127 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnrichedCamera is a device and media service accepting admin:secret
+// with HTTP basic authentication. The stream URI of profile "sub" fails.
+func fakeEnrichedCamera(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request := string(body)
+
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin" || password != "secret" || strings.Contains(request, "UsernameToken") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="camera"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var response string
+		switch {
+		case strings.Contains(request, "GetDeviceInformation"):
+			response = `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer><tds:Model>C1</tds:Model><tds:SerialNumber>42</tds:SerialNumber><tds:FirmwareVersion>1.2</tds:FirmwareVersion></tds:GetDeviceInformationResponse>`
+		case strings.Contains(request, "GetCapabilities"):
+			response = `<tds:GetCapabilitiesResponse><tds:Capabilities><tt:Media><tt:XAddr>` + server.URL + `/onvif/media_service</tt:XAddr></tt:Media></tds:Capabilities></tds:GetCapabilitiesResponse>`
+		case strings.Contains(request, "GetProfiles"):
+			if r.URL.Path != "/onvif/media_service" {
+				t.Errorf("profiles asked from %s", r.URL.Path)
+			}
+			response = `<trt:GetProfilesResponse>
+				<trt:Profiles token="main"><tt:Name>Main</tt:Name><tt:VideoEncoderConfiguration token="enc0"><tt:Encoding>H264</tt:Encoding><tt:Resolution><tt:Width>1920</tt:Width><tt:Height>1080</tt:Height></tt:Resolution></tt:VideoEncoderConfiguration></trt:Profiles>
+				<trt:Profiles token="sub"><tt:Name>Sub</tt:Name></trt:Profiles>
+			</trt:GetProfilesResponse>`
+		case strings.Contains(request, "<trt:ProfileToken>main</trt:ProfileToken>"):
+			response = `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://camera/main</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl" xmlns:trt="http://www.onvif.org/ver10/media/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema"><s:Body>` +
+			response + `</s:Body></s:Envelope>`))
+	}))
+	return server
+}
+
+func TestEnricher(t *testing.T) {
+	server := fakeEnrichedCamera(t)
+	defer server.Close()
+
+	discovered := []DiscoveredDevice{
+		{ID: "camera", XAddrs: []string{server.URL + "/onvif/device_service"}},
+		{ID: "gone", XAddrs: []string{"http://127.0.0.1:1/onvif/device_service"}},
+		{ID: "no-address"},
+	}
+	// The fake camera only accepts HTTP Basic
+	client := NewClient()
+	enricher := Enricher{
+		Credentials: []Credentials{{User: "admin", Password: "admin"}, {User: "admin", Password: "secret"}},
+		Backoff:     time.Millisecond,
+		AuthMode:    AuthHTTPBasic,
+		Workers:     2,
+		Client:      client,
+	}
+	devices := enricher.Enrich(discovered)
+	if len(devices) != len(discovered) {
+		t.Fatalf("got %d devices, want %d", len(devices), len(discovered))
+	}
+
+	camera := devices[0]
+	if camera.Discovered.ID != "camera" || camera.Device.Password != "secret" {
+		t.Errorf("unexpected device %+v", camera.Device)
+	}
+	if camera.Information.Manufacturer != "Acme" || camera.Information.SerialNumber != "42" || camera.Information.FirmwareVersion != "1.2" {
+		t.Errorf("unexpected information %+v", camera.Information)
+	}
+	if camera.Capabilities.Media.XAddr != server.URL+"/onvif/media_service" {
+		t.Errorf("media XAddr %q", camera.Capabilities.Media.XAddr)
+	}
+	if len(camera.Profiles) != 2 || len(camera.Streams) != 1 {
+		t.Fatalf("got %d profiles and %d streams", len(camera.Profiles), len(camera.Streams))
+	}
+	if stream := camera.Streams[0]; stream.StreamURI != "rtsp://camera/main" || stream.Resolution.Height != 1080 || stream.VideoCodec != "H264" {
+		t.Errorf("unexpected stream %+v", stream)
+	}
+	if camera.Err == nil || !strings.Contains(camera.Err.Error(), "sub") {
+		t.Errorf("got error %v, want the failure of the sub stream", camera.Err)
+	}
+
+	if devices[1].Err == nil || devices[1].Device.Password != "" {
+		t.Errorf("unreachable device enriched: %+v", devices[1])
+	}
+	if !errors.Is(devices[2].Err, errNoXAddr) {
+		t.Errorf("got %v for a device without XAddr", devices[2].Err)
+	}
+
+	// Rejected credentials are reported as such
+	devices = Enricher{Credentials: []Credentials{{User: "admin", Password: "admin"}}, AuthMode: AuthHTTPBasic, Client: client}.Enrich(discovered[:1])
+	if !IsNotAuthorized(devices[0].Err) {
+		t.Errorf("got %v, want a not authorized error", devices[0].Err)
+	}
+
+	// The attempts are limited as by CredentialChecker
+	limited := enricher
+	limited.MaxAttempts = 1
+	devices = limited.Enrich(discovered[:1])
+	if !errors.Is(devices[0].Err, ErrAttemptLimit) {
+		t.Errorf("got %v, want ErrAttemptLimit", devices[0].Err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	devices = enricher.EnrichContext(ctx, discovered)
+	for _, device := range devices {
+		if device.Err != context.Canceled {
+			t.Errorf("got %v after cancel", device.Err)
+		}
+	}
+}
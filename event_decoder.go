@@ -0,0 +1,194 @@
+package onvif
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedTopic is returned by DecodeEvent for the topics it has no
+// decoder for
+var ErrUnsupportedTopic = errors.New("onvif: unsupported event topic")
+
+// PropertyOperation tells why the message of a property topic was sent
+type PropertyOperation string
+
+// Operations of the messages of property topics
+const (
+	PropertyInitialized PropertyOperation = "Initialized"
+	PropertyChanged     PropertyOperation = "Changed"
+	PropertyDeleted     PropertyOperation = "Deleted"
+)
+
+// EventKind is the kind of the events decoded by DecodeEvent
+type EventKind string
+
+// Kinds of the events decoded by DecodeEvent
+const (
+	EventMotion         EventKind = "Motion"
+	EventTamper         EventKind = "Tamper"
+	EventDigitalInput   EventKind = "DigitalInput"
+	EventRelay          EventKind = "Relay"
+	EventLineCrossing   EventKind = "LineCrossing"
+	EventFieldDetection EventKind = "FieldDetection"
+)
+
+// Event is a notification message of a common topic, decoded whatever the
+// variant of the topic the device sends
+type Event struct {
+	Kind  EventKind
+	Topic string
+
+	// Time is the UtcTime of the message, or zero when it has none
+	Time time.Time
+
+	// Operation is empty for the topics which aren't properties, such as
+	// line crossings
+	Operation PropertyOperation
+
+	// Source is the token of the source of the event, e.g. the video source
+	// configuration, the digital input or the relay
+	Source string
+
+	// Rule is the name of the analytics rule which raised the event
+	Rule string
+
+	// ObjectID identifies the object which crossed a line
+	ObjectID string
+
+	// Active is the state of the property: motion or tampering detected,
+	// input or relay active, objects inside the field. It is always set for
+	// line crossings.
+	Active bool
+
+	Message NotificationMessage
+}
+
+// eventTopic describes the items of the messages of a topic
+type eventTopic struct {
+	kind EventKind
+
+	// pattern is the topic without namespace prefixes, where * matches any
+	// segment
+	pattern string
+
+	// source and state list the names of the items, the first one found is
+	// used. Topics without state are events, always active.
+	source []string
+	state  []string
+}
+
+// eventTopics are the topics decoded by DecodeEvent, including the variants
+// sent by some vendors
+var eventTopics = []eventTopic{
+	{EventMotion, "VideoSource/MotionAlarm", []string{"Source", "VideoSourceToken"}, []string{"State"}},
+	{EventMotion, "RuleEngine/CellMotionDetector/Motion", []string{"VideoSourceConfigurationToken"}, []string{"IsMotion"}},
+	{EventMotion, "RuleEngine/MotionRegionDetector/Motion", []string{"VideoSourceConfigurationToken", "Source"}, []string{"State", "IsMotion"}},
+	{EventMotion, "CameraApplicationPlatform/VMD/*", nil, []string{"active"}},
+	{EventTamper, "VideoSource/GlobalSceneChange/*", []string{"Source", "VideoSourceToken"}, []string{"State"}},
+	{EventTamper, "RuleEngine/TamperDetector/Tamper", []string{"VideoSourceConfigurationToken"}, []string{"IsTamper"}},
+	{EventTamper, "VideoSource/Tampering", []string{"channel"}, []string{"tampering"}},
+	{EventDigitalInput, "Device/Trigger/DigitalInput", []string{"InputToken", "DigitalInputToken"}, []string{"LogicalState", "State"}},
+	{EventDigitalInput, "Device/IO/Port", []string{"port"}, []string{"state"}},
+	{EventDigitalInput, "Device/IO/VirtualInput", []string{"port"}, []string{"active"}},
+	{EventRelay, "Device/Trigger/Relay", []string{"RelayToken"}, []string{"LogicalState"}},
+	{EventRelay, "Device/IO/OutputPort", []string{"port"}, []string{"state"}},
+	{EventLineCrossing, "RuleEngine/LineDetector/Crossed", []string{"VideoSourceConfigurationToken"}, nil},
+	{EventLineCrossing, "CameraApplicationPlatform/CrossLineDetection/*", nil, []string{"active"}},
+	{EventFieldDetection, "RuleEngine/FieldDetector/ObjectsInside", []string{"VideoSourceConfigurationToken"}, []string{"IsInside"}},
+	{EventFieldDetection, "CameraApplicationPlatform/FenceGuard/*", nil, []string{"active"}},
+}
+
+// DecodeEvent decodes a notification message of a motion, tamper, digital
+// input, relay, line crossing or field detection topic. An error matching
+// ErrUnsupportedTopic is returned for the other topics.
+func DecodeEvent(message NotificationMessage) (Event, error) {
+	var topic *eventTopic
+	for i := range eventTopics {
+		if matchEventTopic(eventTopics[i].pattern, message.Topic) {
+			topic = &eventTopics[i]
+			break
+		}
+	}
+	if topic == nil {
+		return Event{}, fmt.Errorf("%w: %s", ErrUnsupportedTopic, message.Topic)
+	}
+
+	event := Event{
+		Kind:      topic.kind,
+		Topic:     message.Topic,
+		Operation: message.PropertyOperation,
+		Message:   message,
+	}
+	if strings.TrimSpace(message.UtcTime) != "" {
+		utcTime, err := parseDateTime(message.UtcTime)
+		if err != nil {
+			return Event{}, fmt.Errorf("onvif: decoding UtcTime: %w", err)
+		}
+		event.Time = utcTime
+	}
+
+	event.Source, _ = messageItem(message.Source, topic.source...)
+	event.Rule, _ = messageItem(message.Source, "Rule")
+	event.ObjectID, _ = messageItem(message.Data, "ObjectId")
+
+	if topic.state == nil {
+		event.Active = true
+		return event, nil
+	}
+
+	// The state is missing from the messages of deleted properties
+	value, ok := messageItem(message.Data, topic.state...)
+	if !ok {
+		return event, nil
+	}
+	active, err := parseEventState(value)
+	if err != nil {
+		return Event{}, fmt.Errorf("onvif: decoding the state of %s: %w", message.Topic, err)
+	}
+	event.Active = active
+	return event, nil
+}
+
+// matchEventTopic reports whether topic, once its namespace prefixes are
+// removed, matches pattern
+func matchEventTopic(pattern, topic string) bool {
+	patterns := strings.Split(pattern, "/")
+	segments := strings.Split(strings.TrimSpace(topic), "/")
+	if len(patterns) != len(segments) {
+		return false
+	}
+	for i, segment := range segments {
+		if patterns[i] != "*" && patterns[i] != localName(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// messageItem returns the value of the first of the items with one of the
+// names
+func messageItem(items []MessageData, names ...string) (string, bool) {
+	for _, name := range names {
+		for _, item := range items {
+			if item.Name == name {
+				return strings.TrimSpace(item.Value), true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseEventState parses the state of a property, an xs:boolean or the
+// tt:RelayLogicalState of relays
+func parseEventState(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "active":
+		return true, nil
+	case "inactive":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
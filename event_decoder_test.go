@@ -0,0 +1,129 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	var notify struct {
+		NotificationMessage []notificationMessageXML `xml:"Body>Notify>NotificationMessage"`
+	}
+	body := `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"><env:Body><wsnt:Notify><wsnt:NotificationMessage>
+		<wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:RuleEngine/CellMotionDetector/Motion</wsnt:Topic>
+		<wsnt:Message>
+			<tt:Message UtcTime="2020-01-02T03:04:05.5Z" PropertyOperation="Initialized">
+				<tt:Source>
+					<tt:SimpleItem Name="VideoSourceConfigurationToken" Value="VideoSourceToken"/>
+					<tt:SimpleItem Name="Rule" Value="MyMotionDetectorRule"/>
+				</tt:Source>
+				<tt:Data><tt:SimpleItem Name="IsMotion" Value="true"/></tt:Data>
+			</tt:Message>
+		</wsnt:Message>
+	</wsnt:NotificationMessage></wsnt:Notify></env:Body></env:Envelope>`
+	if err := xml.Unmarshal([]byte(body), &notify); err != nil || len(notify.NotificationMessage) != 1 {
+		t.Fatalf("notify not decoded: %v", err)
+	}
+	message := notify.NotificationMessage[0].message()
+	if message.PropertyOperation != PropertyInitialized {
+		t.Errorf("got property operation %q", message.PropertyOperation)
+	}
+
+	event, err := DecodeEvent(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Event{
+		Kind:      EventMotion,
+		Topic:     "tns1:RuleEngine/CellMotionDetector/Motion",
+		Time:      time.Date(2020, 1, 2, 3, 4, 5, 500000000, time.UTC),
+		Operation: PropertyInitialized,
+		Source:    "VideoSourceToken",
+		Rule:      "MyMotionDetectorRule",
+		Active:    true,
+	}
+	if event.Kind != want.Kind || event.Topic != want.Topic || !event.Time.Equal(want.Time) || event.Operation != want.Operation ||
+		event.Source != want.Source || event.Rule != want.Rule || event.Active != want.Active {
+		t.Errorf("got %+v, want %+v", event, want)
+	}
+
+	for _, test := range []struct {
+		message NotificationMessage
+		kind    EventKind
+		source  string
+		active  bool
+	}{
+		{
+			NotificationMessage{Topic: "tns1:VideoSource/MotionAlarm", Source: []MessageData{{"Source", "VideoSource_1"}}, Data: []MessageData{{"State", "false"}}},
+			EventMotion, "VideoSource_1", false,
+		},
+		{
+			NotificationMessage{Topic: "tnsaxis:CameraApplicationPlatform/VMD/Camera1ProfileANY", Data: []MessageData{{"active", "1"}}},
+			EventMotion, "", true,
+		},
+		{
+			NotificationMessage{Topic: "tns1:VideoSource/GlobalSceneChange/ImagingService", Source: []MessageData{{"Source", "VideoSource_1"}}, Data: []MessageData{{"State", "true"}}},
+			EventTamper, "VideoSource_1", true,
+		},
+		{
+			NotificationMessage{Topic: "tns1:RuleEngine/TamperDetector/Tamper", Data: []MessageData{{"IsTamper", "true"}}},
+			EventTamper, "", true,
+		},
+		{
+			NotificationMessage{Topic: "tns1:Device/Trigger/DigitalInput", Source: []MessageData{{"InputToken", "DI_1"}}, Data: []MessageData{{"LogicalState", "true"}}},
+			EventDigitalInput, "DI_1", true,
+		},
+		{
+			NotificationMessage{Topic: "tnsaxis:Device/IO/Port", Source: []MessageData{{"port", "1"}}, Data: []MessageData{{"state", "0"}}},
+			EventDigitalInput, "1", false,
+		},
+		{
+			NotificationMessage{Topic: "tns1:Device/Trigger/Relay", Source: []MessageData{{"RelayToken", "Relay_1"}}, Data: []MessageData{{"LogicalState", "active"}}},
+			EventRelay, "Relay_1", true,
+		},
+		{
+			NotificationMessage{Topic: "tns1:Device/Trigger/Relay", Source: []MessageData{{"RelayToken", "Relay_1"}}, Data: []MessageData{{"LogicalState", "inactive"}}},
+			EventRelay, "Relay_1", false,
+		},
+		{
+			NotificationMessage{Topic: "tns1:RuleEngine/LineDetector/Crossed", Source: []MessageData{{"VideoSourceConfigurationToken", "VSC_1"}}, Data: []MessageData{{"ObjectId", "42"}}},
+			EventLineCrossing, "VSC_1", true,
+		},
+		{
+			NotificationMessage{Topic: "tnsaxis:CameraApplicationPlatform/CrossLineDetection/ClineDetection", Data: []MessageData{{"active", "1"}}},
+			EventLineCrossing, "", true,
+		},
+		{
+			NotificationMessage{Topic: "ns0:RuleEngine/FieldDetector/ObjectsInside", Source: []MessageData{{"VideoSourceConfigurationToken", "VSC_1"}}, Data: []MessageData{{"IsInside", "false"}}},
+			EventFieldDetection, "VSC_1", false,
+		},
+		{
+			NotificationMessage{Topic: "tns1:RuleEngine/FieldDetector/ObjectsInside", PropertyOperation: PropertyDeleted},
+			EventFieldDetection, "", false,
+		},
+	} {
+		event, err := DecodeEvent(test.message)
+		if err != nil {
+			t.Errorf("%s: %v", test.message.Topic, err)
+			continue
+		}
+		if event.Kind != test.kind || event.Source != test.source || event.Active != test.active || !event.Time.IsZero() {
+			t.Errorf("%s: got %+v", test.message.Topic, event)
+		}
+	}
+
+	if event, err := DecodeEvent(NotificationMessage{Topic: "tns1:RuleEngine/LineDetector/Crossed", Data: []MessageData{{"ObjectId", "42"}}}); err != nil || event.ObjectID != "42" {
+		t.Errorf("got %+v, %v", event, err)
+	}
+	if _, err := DecodeEvent(NotificationMessage{Topic: "tns1:Monitoring/ProcessorUsage"}); !errors.Is(err, ErrUnsupportedTopic) {
+		t.Errorf("got %v, want ErrUnsupportedTopic", err)
+	}
+	if _, err := DecodeEvent(NotificationMessage{Topic: "tns1:VideoSource/MotionAlarm", Data: []MessageData{{"State", "maybe"}}}); err == nil {
+		t.Error("invalid state decoded")
+	}
+	if _, err := DecodeEvent(NotificationMessage{Topic: "tns1:VideoSource/MotionAlarm", UtcTime: "yesterday"}); err == nil {
+		t.Error("invalid time decoded")
+	}
+}
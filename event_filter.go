@@ -0,0 +1,101 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"sort"
+)
+
+// Dialects of the expressions of an EventFilter. The ItemFilter dialect is
+// the subset of XPath defined by ONVIF to select the items of messages.
+const (
+	TopicExpressionConcreteSet = "http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet"
+	MessageContentItemFilter   = "http://www.onvif.org/ver10/tev/messageContentFilter/ItemFilter"
+)
+
+// eventFilterNamespaces are declared for the prefixes of every EventFilter
+var eventFilterNamespaces = map[string]string{
+	"tns1": "http://www.onvif.org/ver10/topics",
+	"tt":   "http://www.onvif.org/ver10/schema",
+}
+
+// EventFilter selects the notifications sent for a subscription, so that
+// the device doesn't send the events the client isn't interested in. The
+// zero value selects every notification.
+type EventFilter struct {
+	// TopicExpression selects topics in the ConcreteSet dialect, e.g.
+	// tns1:RuleEngine/CellMotionDetector/Motion. Topics are separated by |.
+	TopicExpression string
+
+	// MessageContent selects messages with an XPath expression on their
+	// items, e.g. boolean(//tt:SimpleItem[@Name="IsMotion" and
+	// @Value="true"])
+	MessageContent string
+
+	// Namespaces declares the prefixes used by the expressions besides
+	// tns1 and tt, e.g. tnsaxis for the topics of Axis devices
+	Namespaces map[string]string
+}
+
+// xml returns the filter as the element with the given name, or nothing
+// when it selects every notification. CreatePullPointSubscription takes a
+// tev:Filter, which inherits the default namespace of the request, and the
+// WS-BaseNotification Subscribe a wsnt:Filter.
+func (filter EventFilter) xml(element string) string {
+	if filter.TopicExpression == "" && filter.MessageContent == "" {
+		return ""
+	}
+
+	namespaces := make(map[string]string, len(eventFilterNamespaces)+len(filter.Namespaces))
+	for prefix, namespace := range eventFilterNamespaces {
+		namespaces[prefix] = namespace
+	}
+	for prefix, namespace := range filter.Namespaces {
+		namespaces[prefix] = namespace
+	}
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	declarations := ""
+	for _, prefix := range prefixes {
+		declarations += ` xmlns:` + prefix + `="` + xmlEscape(namespaces[prefix]) + `"`
+	}
+
+	body := `<` + element + ` xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"` + declarations + `>`
+	if filter.TopicExpression != "" {
+		body += `<wsnt:TopicExpression Dialect="` + TopicExpressionConcreteSet + `">` + xmlEscape(filter.TopicExpression) + `</wsnt:TopicExpression>`
+	}
+	if filter.MessageContent != "" {
+		body += `<wsnt:MessageContent Dialect="` + MessageContentItemFilter + `">` + xmlEscape(filter.MessageContent) + `</wsnt:MessageContent>`
+	}
+	return body + `</` + element + `>`
+}
+
+// SetSynchronizationPoint asks the device to send again the current state
+// of the properties selected by the subscription at address, as it does
+// when the subscription is created
+func (device Device) SetSynchronizationPoint(address string) error {
+	return device.SetSynchronizationPointContext(context.Background(), address)
+}
+
+// SetSynchronizationPointContext is like SetSynchronizationPoint but uses ctx for the request.
+func (device Device) SetSynchronizationPointContext(ctx context.Context, address string) error {
+	// create soap
+	soap := SOAP{
+		User:     device.User,
+		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
+		Action:   "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/SetSynchronizationPointRequest",
+		Body:     `<SetSynchronizationPoint xmlns="http://www.onvif.org/ver10/events/wsdl"/>`,
+	}
+
+	// send request
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/events/wsdl SetSynchronizationPointResponse"`
+	}
+	return soap.CallContext(ctx, address, &response)
+}
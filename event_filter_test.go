@@ -0,0 +1,107 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEventFilter(t *testing.T) {
+	if filter := (EventFilter{}).xml("Filter"); filter != "" {
+		t.Errorf("empty filter gives %q", filter)
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, string(body))
+		mu.Unlock()
+
+		response := `<tev:SetSynchronizationPointResponse/>`
+		if strings.Contains(string(body), "CreatePullPointSubscription") {
+			response = `<tev:CreatePullPointSubscriptionResponse><tev:SubscriptionReference><wsa:Address>http://` + r.Host + `/subscription</wsa:Address></tev:SubscriptionReference></tev:CreatePullPointSubscriptionResponse>`
+		}
+		if strings.Contains(string(body), "<wsnt:Subscribe") {
+			response = `<wsnt:SubscribeResponse><wsnt:SubscriptionReference><wsa:Address>http://` + r.Host + `/subscription</wsa:Address></wsnt:SubscriptionReference></wsnt:SubscribeResponse>`
+		}
+		w.Write([]byte(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tev="http://www.onvif.org/ver10/events/wsdl" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:wsa="http://www.w3.org/2005/08/addressing"><env:Body>` +
+			response + `</env:Body></env:Envelope>`))
+	}))
+	defer server.Close()
+
+	filter := EventFilter{
+		TopicExpression: "tns1:RuleEngine/CellMotionDetector/Motion|tnsaxis:CameraApplicationPlatform/VMD",
+		MessageContent:  `boolean(//tt:SimpleItem[@Name="IsMotion" and @Value="true"])`,
+		Namespaces:      map[string]string{"tnsaxis": "http://www.axis.com/2009/event/topics"},
+	}
+	stream, err := Device{XAddr: server.URL}.NewEventStream(EventStreamOptions{Filter: filter, InitialState: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	if _, err := (Device{XAddr: server.URL}).SubscribeWithFilter("http://consumer/events", filter); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) < 2 || !strings.Contains(requests[1], "SetSynchronizationPoint") {
+		t.Fatalf("no synchronization point requested after the subscription: %q", requests)
+	}
+
+	var envelope struct {
+		Filter struct {
+			XMLName         xml.Name
+			TopicExpression struct {
+				Dialect    string `xml:"Dialect,attr"`
+				Expression string `xml:",chardata"`
+			}
+			MessageContent struct {
+				Dialect    string `xml:"Dialect,attr"`
+				Expression string `xml:",chardata"`
+			}
+		} `xml:"Body>CreatePullPointSubscription>Filter"`
+	}
+	if err := xml.Unmarshal([]byte(requests[0]), &envelope); err != nil {
+		t.Fatalf("request is not well-formed: %v\n%s", err, requests[0])
+	}
+	if space := envelope.Filter.XMLName.Space; space != "http://www.onvif.org/ver10/events/wsdl" {
+		t.Errorf("Filter in namespace %q, want the events namespace", space)
+	}
+	topic, content := envelope.Filter.TopicExpression, envelope.Filter.MessageContent
+	if topic.Dialect != TopicExpressionConcreteSet || topic.Expression != filter.TopicExpression {
+		t.Errorf("unexpected topic expression %+v", topic)
+	}
+	if content.Dialect != MessageContentItemFilter || content.Expression != filter.MessageContent {
+		t.Errorf("unexpected message content %+v", content)
+	}
+	for _, declaration := range []string{
+		`xmlns:tns1="http://www.onvif.org/ver10/topics"`,
+		`xmlns:tt="http://www.onvif.org/ver10/schema"`,
+		`xmlns:tnsaxis="http://www.axis.com/2009/event/topics"`,
+	} {
+		if !strings.Contains(requests[0], declaration) {
+			t.Errorf("%s is not declared", declaration)
+		}
+	}
+
+	// The push subscription takes a WS-BaseNotification filter
+	var subscribe struct {
+		Filter struct {
+			XMLName xml.Name
+		} `xml:"Body>Subscribe>Filter"`
+	}
+	last := requests[len(requests)-1]
+	if err := xml.Unmarshal([]byte(last), &subscribe); err != nil {
+		t.Fatalf("request is not well-formed: %v\n%s", err, last)
+	}
+	if space := subscribe.Filter.XMLName.Space; space != "http://docs.oasis-open.org/wsn/b-2" {
+		t.Errorf("Subscribe filter in namespace %q", space)
+	}
+}
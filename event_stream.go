@@ -0,0 +1,347 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of EventStreamOptions
+const (
+	defaultPullTimeout  = 10 * time.Second
+	defaultMessageLimit = 100
+	defaultEventBackoff = time.Second
+	defaultMaxBackoff   = time.Minute
+)
+
+const (
+	// eventRequestTimeout bounds the requests of an EventStream, beyond
+	// the time the device may wait for events
+	eventRequestTimeout = 10 * time.Second
+
+	// fallbackSubscriptionLifetime is assumed for the subscriptions whose
+	// termination time can't be read, so that they are renewed early
+	fallbackSubscriptionLifetime = time.Minute
+
+	// maxSubscriptionFailures is the number of pulls or renewals failing in
+	// a row after which the stream subscribes again, whatever the errors
+	maxSubscriptionFailures = 5
+)
+
+// errNoSubscriptionAddress is returned when a device creates a subscription
+// without telling its address
+var errNoSubscriptionAddress = errors.New("onvif: subscription has no address")
+
+// EventStreamOptions configures an EventStream
+type EventStreamOptions struct {
+	// PullTimeout is how long the device may wait for events before it
+	// answers a PullMessages, 10 seconds when zero. It is not used by push
+	// subscriptions, nor is MessageLimit.
+	PullTimeout time.Duration
+
+	// MessageLimit is the number of messages pulled at once at most, 100
+	// when zero
+	MessageLimit int
+
+	// Filter selects the notifications of the subscription
+	Filter EventFilter
+
+	// InitialState asks the device for the current state of the selected
+	// properties after every subscription, with SetSynchronizationPoint,
+	// so that the stream starts with it even after a resubscription
+	InitialState bool
+
+	// Backoff is the delay before retrying after an error, doubled after
+	// every failed retry up to MaxBackoff. They are 1 second and 1 minute
+	// when zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// EventStream delivers the events of a device from a PullPoint
+// subscription, or from a subscription of a NotificationConsumer to which
+// the device posts them. It renews the subscription before it terminates,
+// subscribes again when the device lost it, e.g. after a reboot, and
+// unsubscribes when it is closed.
+type EventStream struct {
+	device   Device
+	options  EventStreamOptions
+	messages chan NotificationMessage
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	closeErr error
+
+	// The consumer of a push subscription, and the URL of the stream on it
+	consumer    *NotificationConsumer
+	consumerURL string
+	id          string
+	deliveries  sync.WaitGroup
+
+	// The subscription, only used by the goroutine of the stream once it
+	// is started
+	address   string
+	renewAt   time.Time
+	expiresAt time.Time
+	failures  int
+}
+
+// NewEventStream creates a PullPoint subscription on the device and starts
+// pulling its messages, which are received from Messages until Close is
+// called. Errors after the subscription are logged and retried.
+func (device Device) NewEventStream(options EventStreamOptions) (*EventStream, error) {
+	return device.NewEventStreamContext(context.Background(), options)
+}
+
+// NewEventStreamContext is like NewEventStream but uses ctx for the request.
+func (device Device) NewEventStreamContext(ctx context.Context, options EventStreamOptions) (*EventStream, error) {
+	stream := newEventStream(device, options)
+	if err := stream.subscribe(ctx); err != nil {
+		stream.cancel()
+		return nil, err
+	}
+
+	stream.start()
+	return stream, nil
+}
+
+// newEventStream returns a stream of the device, without subscription
+func newEventStream(device Device, options EventStreamOptions) *EventStream {
+	if options.PullTimeout <= 0 {
+		options.PullTimeout = defaultPullTimeout
+	}
+	if options.MessageLimit <= 0 {
+		options.MessageLimit = defaultMessageLimit
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = defaultEventBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaultMaxBackoff
+	}
+
+	// The stream runs until it is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventStream{
+		device:   device,
+		options:  options,
+		messages: make(chan NotificationMessage),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// start starts the goroutine of the stream, once it is subscribed
+func (stream *EventStream) start() {
+	go stream.run(stream.ctx)
+}
+
+// Messages returns the channel of the notifications. It is closed when the
+// stream is closed.
+func (stream *EventStream) Messages() <-chan NotificationMessage {
+	return stream.messages
+}
+
+// Close stops the stream and unsubscribes from the device. It returns the
+// error of the unsubscription.
+func (stream *EventStream) Close() error {
+	stream.cancel()
+	<-stream.done
+	return stream.closeErr
+}
+
+// run makes the requests of the subscription until ctx is done, then
+// unsubscribes
+func (stream *EventStream) run(ctx context.Context) {
+	defer close(stream.done)
+	defer close(stream.messages)
+
+	logger := stream.device.logger()
+	backoff := stream.options.Backoff
+	for ctx.Err() == nil {
+		err := stream.step(ctx)
+		if err == nil {
+			backoff = stream.options.Backoff
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		logger.Log(LevelWarn, "event stream request failed", "xaddr", stream.device.XAddr, "subscription", stream.address, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+		backoff *= 2
+		if backoff > stream.options.MaxBackoff {
+			backoff = stream.options.MaxBackoff
+		}
+	}
+
+	// Wait for the messages being posted, before closing the channel
+	if stream.consumer != nil {
+		stream.consumer.remove(stream.id)
+		stream.deliveries.Wait()
+	}
+
+	if stream.address == "" {
+		return
+	}
+	unsubscribeCtx, cancel := context.WithTimeout(context.Background(), eventRequestTimeout)
+	defer cancel()
+	stream.closeErr = stream.device.UnSubscribeContext(unsubscribeCtx, stream.address)
+}
+
+// step makes the next request of the subscription: subscribing when there
+// is none, renewing it when due, or else pulling messages
+func (stream *EventStream) step(ctx context.Context) error {
+	now := time.Now()
+	if stream.address == "" || !now.Before(stream.expiresAt) {
+		stream.address = ""
+		requestCtx, cancel := context.WithTimeout(ctx, eventRequestTimeout)
+		defer cancel()
+		return stream.subscribe(requestCtx)
+	}
+
+	if !now.Before(stream.renewAt) {
+		requestCtx, cancel := context.WithTimeout(ctx, eventRequestTimeout)
+		defer cancel()
+		response, err := stream.device.ReNewContext(requestCtx, stream.address)
+		if err != nil {
+			stream.forget(ctx, err)
+			return err
+		}
+		stream.failures = 0
+		stream.schedule(response.CurrentTime, response.TerminationTime)
+		return nil
+	}
+
+	// The device posts the messages of a push subscription to the
+	// consumer, there is nothing to do until the renewal
+	if stream.consumer != nil {
+		timer := time.NewTimer(stream.renewAt.Sub(now))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+
+	// Don't wait for events beyond the renewal
+	timeout := stream.options.PullTimeout
+	if untilRenew := stream.renewAt.Sub(now); untilRenew < timeout {
+		timeout = untilRenew
+	}
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+
+	requestCtx, cancel := context.WithTimeout(ctx, timeout+eventRequestTimeout)
+	defer cancel()
+	messages, err := stream.device.pullMessages(requestCtx, stream.address, timeout, stream.options.MessageLimit)
+	if err != nil {
+		stream.forget(ctx, err)
+		return err
+	}
+	stream.failures = 0
+
+	for _, message := range messages {
+		select {
+		case stream.messages <- message:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// subscribe creates the PullPoint subscription, or the push subscription
+// to the consumer
+func (stream *EventStream) subscribe(ctx context.Context) error {
+	var response CreatePullPointSubscriptionResponse
+	var err error
+	if stream.consumer != nil {
+		response, err = stream.device.subscribe(ctx, stream.consumerURL, stream.options.Filter)
+	} else {
+		response, err = stream.device.CreatePullPointSubscriptionWithFilterContext(ctx, stream.options.Filter)
+	}
+	if err != nil {
+		return err
+	}
+
+	address := strings.TrimSpace(response.SubscriptionReference.Address)
+	if address == "" {
+		return errNoSubscriptionAddress
+	}
+
+	stream.address = address
+	stream.failures = 0
+	stream.schedule(response.CurrentTime, response.TerminationTime)
+
+	// Devices which can't send the state again still send the changes
+	if stream.options.InitialState {
+		if err := stream.device.SetSynchronizationPointContext(ctx, address); err != nil {
+			stream.device.logger().Log(LevelWarn, "set synchronization point failed", "subscription", address, "error", err)
+		}
+	}
+	return nil
+}
+
+// schedule sets when the subscription terminates and when to renew it,
+// once three quarters of its lifetime have elapsed. The lifetime is
+// computed from the times of the device, whose clock may be off.
+func (stream *EventStream) schedule(currentTime, terminationTime string) {
+	lifetime := fallbackSubscriptionLifetime
+	current, currentErr := parseDateTime(currentTime)
+	termination, terminationErr := parseDateTime(terminationTime)
+	if currentErr == nil && terminationErr == nil && termination.After(current) {
+		lifetime = termination.Sub(current)
+	}
+
+	now := time.Now()
+	stream.expiresAt = now.Add(lifetime)
+	stream.renewAt = now.Add(lifetime * 3 / 4)
+}
+
+// forget drops the subscription when the device doesn't know it anymore,
+// e.g. after a reboot, as told by a ResourceUnknown fault or a 404, or when
+// maxSubscriptionFailures requests failed in a row, as many devices answer
+// with another fault or refuse the connections once rebooted. Until then it
+// is kept, as a busy device or a network error may be over before it
+// terminates, and devices limit their subscriptions. A subscription dropped
+// after failures is unsubscribed, in case the device still has it.
+func (stream *EventStream) forget(ctx context.Context, err error) {
+	if isUnknownSubscription(err) {
+		stream.address = ""
+		return
+	}
+
+	stream.failures++
+	if stream.failures < maxSubscriptionFailures {
+		return
+	}
+	stream.device.logger().Log(LevelWarn, "event subscription abandoned", "xaddr", stream.device.XAddr, "subscription", stream.address, "failures", stream.failures)
+	unsubscribeCtx, cancel := context.WithTimeout(ctx, eventRequestTimeout)
+	defer cancel()
+	stream.device.UnSubscribeContext(unsubscribeCtx, stream.address)
+	stream.address = ""
+}
+
+// isUnknownSubscription reports whether err tells that the device doesn't
+// have the subscription
+func isUnknownSubscription(err error) bool {
+	var fault *SOAPFault
+	if errors.As(err, &fault) {
+		return fault.HasCode("ResourceUnknownFault") || fault.HasCode("ResourceUnknown") || fault.HTTPStatus == http.StatusNotFound
+	}
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,249 @@
+package onvif
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testResourceUnknownFault = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:wsrf-rw="http://docs.oasis-open.org/wsrf/rw-2">
+<env:Body><env:Fault>
+<env:Code><env:Value>env:Receiver</env:Value><env:Subcode><env:Value>wsrf-rw:ResourceUnknownFault</env:Value></env:Subcode></env:Code>
+<env:Reason><env:Text xml:lang="en">Unknown subscription</env:Text></env:Reason>
+</env:Fault></env:Body></env:Envelope>`
+
+const testReceiverFault = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+<env:Body><env:Fault>
+<env:Code><env:Value>env:Receiver</env:Value></env:Code>
+<env:Reason><env:Text xml:lang="en">Internal error</env:Text></env:Reason>
+</env:Fault></env:Body></env:Envelope>`
+
+// fakeEventService is an event service whose subscriptions live for
+// lifetime. Every PullPoint subscription has one message, named after it, on
+// its first pull, and is mapped to whether that message is still pending.
+// The consumers of the push subscriptions are recorded. The pulls fail
+// while the service is busy. The unknown subscriptions are answered with a
+// ResourceUnknown fault, or a plain fault with genericFaults.
+type fakeEventService struct {
+	mu            sync.Mutex
+	lifetime      time.Duration
+	busy          int
+	genericFaults bool
+	subscriptions map[string]bool
+	created       int
+	renewed       int
+	unsubscribed  []string
+	consumers     []string
+}
+
+var consumerPattern = regexp.MustCompile(`<wsa:Address[^>]*>([^<]+)</wsa:Address>`)
+
+func (service *fakeEventService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request := string(body)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	now := time.Now().UTC()
+	times := `<wsnt:CurrentTime>` + now.Format(time.RFC3339Nano) + `</wsnt:CurrentTime>` +
+		`<wsnt:TerminationTime>` + now.Add(service.lifetime).Format(time.RFC3339Nano) + `</wsnt:TerminationTime>`
+
+	var response string
+	switch {
+	case strings.Contains(request, "CreatePullPointSubscription"):
+		service.created++
+		name := fmt.Sprintf("sub%d", service.created)
+		service.subscriptions[name] = true
+		response = `<tev:CreatePullPointSubscriptionResponse><tev:SubscriptionReference><wsa:Address>http://` + r.Host + `/` + name +
+			`</wsa:Address></tev:SubscriptionReference>` + times + `</tev:CreatePullPointSubscriptionResponse>`
+
+	case strings.Contains(request, "<wsnt:Subscribe"):
+		service.created++
+		name := fmt.Sprintf("sub%d", service.created)
+		service.subscriptions[name] = false
+		service.consumers = append(service.consumers, consumerPattern.FindStringSubmatch(request)[1])
+		response = `<wsnt:SubscribeResponse><wsnt:SubscriptionReference><wsa:Address>http://` + r.Host + `/` + name +
+			`</wsa:Address></wsnt:SubscriptionReference>` + times + `</wsnt:SubscribeResponse>`
+
+	case !service.known(strings.Trim(r.URL.Path, "/")):
+		w.WriteHeader(http.StatusBadRequest)
+		if service.genericFaults {
+			w.Write([]byte(testReceiverFault))
+			return
+		}
+		w.Write([]byte(testResourceUnknownFault))
+		return
+
+	case strings.Contains(request, "PullMessages") && service.busy > 0:
+		service.busy--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+
+	case strings.Contains(request, "PullMessages"):
+		name := strings.Trim(r.URL.Path, "/")
+		var messages string
+		if service.subscriptions[name] {
+			service.subscriptions[name] = false
+			messages = `<wsnt:NotificationMessage><wsnt:Topic>tns1:` + name + `</wsnt:Topic><wsnt:Message><tt:Message UtcTime="2020-01-02T03:04:05Z"/></wsnt:Message></wsnt:NotificationMessage>`
+		} else {
+			// Wait a little for events which never come
+			service.mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			service.mu.Lock()
+		}
+		response = `<tev:PullMessagesResponse>` + times + messages + `</tev:PullMessagesResponse>`
+
+	case strings.Contains(request, "Renew"):
+		service.renewed++
+		response = `<wsnt:RenewResponse>` + times + `</wsnt:RenewResponse>`
+
+	case strings.Contains(request, "Unsubscribe"):
+		name := strings.Trim(r.URL.Path, "/")
+		service.unsubscribed = append(service.unsubscribed, name)
+		delete(service.subscriptions, name)
+		response = `<wsnt:UnsubscribeResponse/>`
+	}
+
+	w.Write([]byte(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tev="http://www.onvif.org/ver10/events/wsdl" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:wsa="http://www.w3.org/2005/08/addressing"><env:Body>` +
+		response + `</env:Body></env:Envelope>`))
+}
+
+// known reports whether the service has the subscription
+func (service *fakeEventService) known(name string) bool {
+	_, ok := service.subscriptions[name]
+	return ok
+}
+
+// reboot makes the service forget its subscriptions
+func (service *fakeEventService) reboot() {
+	service.mu.Lock()
+	service.subscriptions = make(map[string]bool)
+	service.mu.Unlock()
+}
+
+func TestEventStream(t *testing.T) {
+	service := &fakeEventService{lifetime: 2 * time.Second, subscriptions: make(map[string]bool)}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	device := Device{XAddr: server.URL + "/onvif/events"}
+	stream, err := device.NewEventStream(EventStreamOptions{Backoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(want string) {
+		select {
+		case message := <-stream.Messages():
+			if message.Topic != want {
+				t.Errorf("got message %q, want %q", message.Topic, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %q message received", want)
+		}
+	}
+	receive("tns1:sub1")
+
+	// The stream subscribes again when the device lost its subscription
+	service.reboot()
+	receive("tns1:sub2")
+
+	// and renews it before it terminates
+	time.Sleep(1800 * time.Millisecond)
+	service.mu.Lock()
+	renewed, created := service.renewed, service.created
+	service.mu.Unlock()
+	if renewed == 0 || created != 2 {
+		t.Errorf("got %d renewals and %d subscriptions, want renewals of the 2nd subscription", renewed, created)
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, open := <-stream.Messages(); open {
+		t.Error("messages not closed")
+	}
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if len(service.unsubscribed) != 1 || service.unsubscribed[0] != "sub2" {
+		t.Errorf("unsubscribed %q, want sub2", service.unsubscribed)
+	}
+}
+
+func TestEventStreamBusyDevice(t *testing.T) {
+	service := &fakeEventService{lifetime: time.Hour, busy: 3, subscriptions: make(map[string]bool)}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	stream, err := Device{XAddr: server.URL + "/onvif/events"}.NewEventStream(EventStreamOptions{Backoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	// The subscription is kept while the device is busy
+	select {
+	case message := <-stream.Messages():
+		if message.Topic != "tns1:sub1" {
+			t.Errorf("got message %q, want tns1:sub1", message.Topic)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.created != 1 || service.busy != 0 {
+		t.Errorf("got %d subscriptions after %d failed pulls, want 1", service.created, 3-service.busy)
+	}
+}
+
+func TestEventStreamRestartedDevice(t *testing.T) {
+	service := &fakeEventService{lifetime: time.Hour, genericFaults: true, subscriptions: make(map[string]bool)}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	stream, err := Device{XAddr: server.URL + "/onvif/events"}.NewEventStream(EventStreamOptions{Backoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	receive := func(want string) {
+		select {
+		case message := <-stream.Messages():
+			if message.Topic != want {
+				t.Errorf("got message %q, want %q", message.Topic, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %q message received", want)
+		}
+	}
+	receive("tns1:sub1")
+
+	// The restarted device answers the pulls with a plain env:Receiver
+	// fault, the stream subscribes again after a few of them
+	service.reboot()
+	receive("tns1:sub2")
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.created != 2 {
+		t.Errorf("got %d subscriptions, want 2", service.created)
+	}
+}
+
+func TestEventStreamSubscriptionFailure(t *testing.T) {
+	server := serveBody(`<tev:CreatePullPointSubscriptionResponse/>`)
+	defer server.Close()
+
+	if _, err := (Device{XAddr: server.URL}).NewEventStream(EventStreamOptions{}); err != errNoSubscriptionAddress {
+		t.Errorf("got %v, want %v", err, errNoSubscriptionAddress)
+	}
+}
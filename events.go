@@ -1,85 +1,130 @@
 package onvif
 
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
 // return url for unsubscribe
 func (device Device) Subscribe(address string) (string, error) {
+	return device.SubscribeContext(context.Background(), address)
+}
+
+// SubscribeContext is like Subscribe but uses ctx for the request.
+func (device Device) SubscribeContext(ctx context.Context, address string) (string, error) {
+	return device.SubscribeWithFilterContext(ctx, address, EventFilter{})
+}
+
+// SubscribeWithFilter is like Subscribe, but the device only posts the
+// notifications selected by filter
+func (device Device) SubscribeWithFilter(address string, filter EventFilter) (string, error) {
+	return device.SubscribeWithFilterContext(context.Background(), address, filter)
+}
+
+// SubscribeWithFilterContext is like SubscribeWithFilter but uses ctx for the request.
+func (device Device) SubscribeWithFilterContext(ctx context.Context, address string, filter EventFilter) (string, error) {
+	response, err := device.subscribe(ctx, address, filter)
+	return response.SubscriptionReference.Address, err
+}
+
+// subscribe asks the device to post the notifications selected by filter to
+// the consumer at address, and returns the subscription with its
+// termination time
+func (device Device) subscribe(ctx context.Context, address string, filter EventFilter) (CreatePullPointSubscriptionResponse, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		XMLNs: []string{
 			`xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"`,
 			`xmlns:wsa="http://www.w3.org/2005/08/addressing"`,
 		},
 		Body: `<wsnt:Subscribe xmlns="http://docs.oasis-open.org/wsn/b-2.xsd">
 					<wsnt:ConsumerReference>
-						<wsa:Address xmlns="http://www.w3.org/2005/08/addressing">` + address + `</wsa:Address>
-					</wsnt:ConsumerReference>
+						<wsa:Address xmlns="http://www.w3.org/2005/08/addressing">` + xmlEscape(address) + `</wsa:Address>
+					</wsnt:ConsumerReference>` + filter.xml("wsnt:Filter") + `
 					<wsnt:InitialTerminationTime>PT3600S</wsnt:InitialTerminationTime>
 				</wsnt:Subscribe>`,
 	}
 
-	var result string = ""
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name `xml:"http://docs.oasis-open.org/wsn/b-2 SubscribeResponse"`
+		CreatePullPointSubscriptionResponse
 	}
-
-	ifaceResult, err := response.ValueForPath("Envelope.Body.SubscribeResponse.SubscriptionReference.Address")
-	if err != nil {
-		return result, err
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return CreatePullPointSubscriptionResponse{}, err
 	}
 
-	result, _ = ifaceResult.(string)
-	return result, nil
+	return response.CreatePullPointSubscriptionResponse, nil
 }
 
 func (device Device) CreatePullPointSubscription() (CreatePullPointSubscriptionResponse, error) {
+	return device.CreatePullPointSubscriptionContext(context.Background())
+}
+
+// CreatePullPointSubscriptionContext is like CreatePullPointSubscription but uses ctx for the request.
+func (device Device) CreatePullPointSubscriptionContext(ctx context.Context) (CreatePullPointSubscriptionResponse, error) {
+	return device.CreatePullPointSubscriptionWithFilterContext(ctx, EventFilter{})
+}
+
+// CreatePullPointSubscriptionWithFilter is like CreatePullPointSubscription,
+// but only the notifications selected by filter are pulled
+func (device Device) CreatePullPointSubscriptionWithFilter(filter EventFilter) (CreatePullPointSubscriptionResponse, error) {
+	return device.CreatePullPointSubscriptionWithFilterContext(context.Background(), filter)
+}
+
+// CreatePullPointSubscriptionWithFilterContext is like CreatePullPointSubscriptionWithFilter but uses ctx for the request.
+func (device Device) CreatePullPointSubscriptionWithFilterContext(ctx context.Context, filter EventFilter) (CreatePullPointSubscriptionResponse, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Action:   "http://www.onvif.org/ver10/events/wsdl/EventPortType/CreatePullPointSubscriptionRequest",
-		Body: `<CreatePullPointSubscription xmlns="http://www.onvif.org/ver10/events/wsdl">
+		Body: `<CreatePullPointSubscription xmlns="http://www.onvif.org/ver10/events/wsdl">` + filter.xml("Filter") + `
 					<InitialTerminationTime>PT3600S</InitialTerminationTime>
 				</CreatePullPointSubscription>`,
 	}
 
-	result := CreatePullPointSubscriptionResponse{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/events/wsdl CreatePullPointSubscriptionResponse"`
+		CreatePullPointSubscriptionResponse
 	}
-
-	ifaceResult, err := response.ValueForPath("Envelope.Body.CreatePullPointSubscriptionResponse")
-	if err != nil {
-		return result, err
-	}
-
-	if mapMetadata, ok := ifaceResult.(map[string]interface{}); ok {
-		result.CurrentTime = interfaceToString(mapMetadata["CurrentTime"])
-		result.TerminationTime = interfaceToString(mapMetadata["TerminationTime"])
-		result.SubscriptionReference = SubscriptionReference{}
-		if mapSubscriptionReference, ok := mapMetadata["SubscriptionReference"].(map[string]interface{}); ok {
-			result.SubscriptionReference.Address = interfaceToString(mapSubscriptionReference["Address"])
-		}
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return CreatePullPointSubscriptionResponse{}, err
 	}
 
-	return result, nil
+	return response.CreatePullPointSubscriptionResponse, nil
 }
 
-// return url for unsubscribe
+// GetEventProperties returns the GetEventPropertiesResponse of the device
+// as a map.
+//
+// Deprecated: use GetTopicSet, which parses the topic tree.
 func (device Device) GetEventProperties() (interface{}, error) {
+	return device.GetEventPropertiesContext(context.Background())
+}
+
+// GetEventPropertiesContext is like GetEventProperties but uses ctx for the request.
+func (device Device) GetEventPropertiesContext(ctx context.Context) (interface{}, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetEventProperties xmlns="http://www.onvif.org/ver10/events/wsdl"/>`,
 	}
 
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
+	response, err := soap.SendRequestContext(ctx, device.XAddr)
 	if err != nil {
 		return "", err
 	}
@@ -94,126 +139,123 @@ func (device Device) GetEventProperties() (interface{}, error) {
 
 // return url for unsubscribe
 func (device Device) PullMessages(address string) ([]NotificationMessage, error) {
+	return device.PullMessagesContext(context.Background(), address)
+}
+
+// PullMessagesContext is like PullMessages but uses ctx for the request.
+func (device Device) PullMessagesContext(ctx context.Context, address string) ([]NotificationMessage, error) {
+	return device.pullMessages(ctx, address, 3*time.Second, 100)
+}
+
+// pullMessages pulls at most limit messages from the subscription at
+// address. The device answers after timeout when it has no message.
+func (device Device) pullMessages(ctx context.Context, address string, timeout time.Duration, limit int) ([]NotificationMessage, error) {
 	// create soap
 	soap := SOAP{
-		User:     device.User,
-		Password: device.Password,
-		Action:   "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/PullMessagesRequest",
+		User:        device.User,
+		Password:    device.Password,
+		Client:      device.Client,
+		AuthMode:    device.AuthMode,
+		deviceXAddr: device.XAddr,
+		Action:      "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/PullMessagesRequest",
 		Body: `<PullMessages xmlns="http://www.onvif.org/ver10/events/wsdl">
-					<Timeout>PT3S</Timeout>
-					<MessageLimit>100</MessageLimit>
+					<Timeout>` + xsdDuration(timeout) + `</Timeout>
+					<MessageLimit>` + intToString(limit) + `</MessageLimit>
 				</PullMessages>`,
 		NoDebug: true,
 	}
 
 	var result = make([]NotificationMessage, 0)
 	// send request
-	response, err := soap.SendRequest(address)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName             xml.Name                 `xml:"http://www.onvif.org/ver10/events/wsdl PullMessagesResponse"`
+		NotificationMessage []notificationMessageXML `xml:"NotificationMessage"`
 	}
-
-	ifaceResult, err := response.ValuesForPath("Envelope.Body.PullMessagesResponse.NotificationMessage")
-	if err != nil {
+	if err := soap.CallContext(ctx, address, &response); err != nil {
 		return result, err
 	}
 
-	for _, notificationMessage := range ifaceResult {
-		if mapNotiMsg, ok := notificationMessage.(map[string]interface{}); ok {
-			msg := NotificationMessage{}
-			if mapTopic, ok := mapNotiMsg["Topic"].(map[string]interface{}); ok {
-				msg.Topic = interfaceToString(mapTopic["#text"])
-			}
-			if mapMsg, ok := mapNotiMsg["Message"].(map[string]interface{}); ok {
-				if mapMsg, ok := mapMsg["Message"].(map[string]interface{}); ok {
-					msg.UtcTime = interfaceToString(mapMsg["-UtcTime"])
-					if mapData, ok := mapMsg["Data"].(map[string]interface{}); ok {
-						if mapSimpleItems, ok := mapData["SimpleItem"].([]interface{}); ok {
-							for _, item := range mapSimpleItems {
-								if mapItem, ok := item.(map[string]interface{}); ok {
-									msg.Data = append(msg.Data, MessageData{
-										Name:  interfaceToString(mapItem["-Name"]),
-										Value: interfaceToString(mapItem["-Value"]),
-									})
-								}
-							}
-						} else if mapSimpleItem, ok := mapData["SimpleItem"].(map[string]interface{}); ok {
-							msg.Data = append(msg.Data, MessageData{
-								Name:  interfaceToString(mapSimpleItem["-Name"]),
-								Value: interfaceToString(mapSimpleItem["-Value"]),
-							})
-						}
-					}
-					if mapSource, ok := mapMsg["Source"].(map[string]interface{}); ok {
-						if mapSimpleItems, ok := mapSource["SimpleItem"].([]interface{}); ok {
-							for _, item := range mapSimpleItems {
-								if mapItem, ok := item.(map[string]interface{}); ok {
-									msg.Source = append(msg.Source, MessageData{
-										Name:  interfaceToString(mapItem["-Name"]),
-										Value: interfaceToString(mapItem["-Value"]),
-									})
-								}
-							}
-						} else if mapSimpleItem, ok := mapSource["SimpleItem"].(map[string]interface{}); ok {
-							msg.Source = append(msg.Source, MessageData{
-								Name:  interfaceToString(mapSimpleItem["-Name"]),
-								Value: interfaceToString(mapSimpleItem["-Value"]),
-							})
-						}
-					}
-				}
-			}
-			result = append(result, msg)
-		}
+	for _, notificationMessage := range response.NotificationMessage {
+		result = append(result, notificationMessage.message())
 	}
 	return result, nil
 }
 
 func (device Device) UnSubscribe(address string) error {
+	return device.UnSubscribeContext(context.Background(), address)
+}
+
+// UnSubscribeContext is like UnSubscribe but uses ctx for the request.
+func (device Device) UnSubscribeContext(ctx context.Context, address string) error {
 	// create soap
 	soap := SOAP{
-		User:     device.User,
-		Password: device.Password,
-		Body:     `<Unsubscribe xmlns="http://docs.oasis-open.org/wsn/b-2"/>`,
+		User:        device.User,
+		Password:    device.Password,
+		Client:      device.Client,
+		AuthMode:    device.AuthMode,
+		deviceXAddr: device.XAddr,
+		Body:        `<Unsubscribe xmlns="http://docs.oasis-open.org/wsn/b-2"/>`,
 	}
+
 	// send request
-	response, err := soap.SendRequest(address)
-	if err != nil {
-		return err
-	}
-	_, err = response.ValueForPath("Envelope.Body.UnsubscribeResponse")
-	if err != nil {
-		return err
+	var response struct {
+		XMLName xml.Name `xml:"http://docs.oasis-open.org/wsn/b-2 UnsubscribeResponse"`
 	}
-
-	return nil
+	return soap.CallContext(ctx, address, &response)
 }
 
 func (device Device) ReNew(address string) (CreatePullPointSubscriptionResponse, error) {
+	return device.ReNewContext(context.Background(), address)
+}
+
+// ReNewContext is like ReNew but uses ctx for the request.
+func (device Device) ReNewContext(ctx context.Context, address string) (CreatePullPointSubscriptionResponse, error) {
 	// create soap
 	soap := SOAP{
-		User:     device.User,
-		Password: device.Password,
+		User:        device.User,
+		Password:    device.Password,
+		Client:      device.Client,
+		AuthMode:    device.AuthMode,
+		deviceXAddr: device.XAddr,
 		Body: `<Renew xmlns="http://docs.oasis-open.org/wsn/b-2">
 						<TerminationTime>PT3600S</TerminationTime>
 					</Renew>`,
 	}
+
 	result := CreatePullPointSubscriptionResponse{}
 
 	// send request
-	response, err := soap.SendRequest(address)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName         xml.Name `xml:"http://docs.oasis-open.org/wsn/b-2 RenewResponse"`
+		CurrentTime     string
+		TerminationTime string
 	}
-
-	ifaceResult, err := response.ValueForPath("Envelope.Body.RenewResponse")
-	if err != nil {
+	if err := soap.CallContext(ctx, address, &response); err != nil {
 		return result, err
 	}
 
-	if mapMetadata, ok := ifaceResult.(map[string]interface{}); ok {
-		result.CurrentTime = interfaceToString(mapMetadata["CurrentTime"])
-		result.TerminationTime = interfaceToString(mapMetadata["TerminationTime"])
-	}
+	result.CurrentTime = response.CurrentTime
+	result.TerminationTime = response.TerminationTime
 	return result, nil
 }
+
+// notificationMessageXML is a wsnt:NotificationMessage as sent by the device
+type notificationMessageXML struct {
+	Topic   string
+	Message struct {
+		UtcTime           string        `xml:"UtcTime,attr"`
+		PropertyOperation string        `xml:"PropertyOperation,attr"`
+		Source            []MessageData `xml:"Source>SimpleItem"`
+		Data              []MessageData `xml:"Data>SimpleItem"`
+	} `xml:"Message>Message"`
+}
+
+func (notification notificationMessageXML) message() NotificationMessage {
+	return NotificationMessage{
+		Topic:             strings.TrimSpace(notification.Topic),
+		UtcTime:           notification.Message.UtcTime,
+		Source:            notification.Message.Source,
+		Data:              notification.Message.Data,
+		PropertyOperation: PropertyOperation(strings.TrimSpace(notification.Message.PropertyOperation)),
+	}
+}
@@ -0,0 +1,180 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by SOAPFault and HTTPError through errors.Is
+var (
+	ErrNotAuthorized      = errors.New("onvif: not authorized")
+	ErrActionNotSupported = errors.New("onvif: action not supported")
+	ErrInvalidArgVal      = errors.New("onvif: invalid argument value")
+)
+
+// SOAPFault is the error returned when a device answers with a SOAP fault.
+// Code and Subcodes keep the qualified names sent by the device, e.g.
+// "env:Sender" and ["ter:NotAuthorized"].
+type SOAPFault struct {
+	Code       string
+	Subcodes   []string
+	Reason     string
+	Detail     string
+	HTTPStatus int
+}
+
+// Error implements the error interface
+func (fault *SOAPFault) Error() string {
+	code := fault.Code
+	if len(fault.Subcodes) > 0 {
+		code = fault.Subcodes[len(fault.Subcodes)-1]
+	}
+	if fault.Reason == "" {
+		return "onvif: SOAP fault " + code
+	}
+	return "onvif: SOAP fault " + code + ": " + fault.Reason
+}
+
+// HasCode reports whether the fault code or one of its subcodes has the
+// given name. The namespace prefix is ignored, so "NotAuthorized" matches
+// "ter:NotAuthorized".
+func (fault *SOAPFault) HasCode(name string) bool {
+	name = localName(name)
+	if localName(fault.Code) == name {
+		return true
+	}
+	for _, subcode := range fault.Subcodes {
+		if localName(subcode) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Is makes the fault match ErrNotAuthorized, ErrActionNotSupported and
+// ErrInvalidArgVal with errors.Is
+func (fault *SOAPFault) Is(target error) bool {
+	switch target {
+	case ErrNotAuthorized:
+		return fault.HasCode("NotAuthorized") || fault.HTTPStatus == http.StatusUnauthorized
+	case ErrActionNotSupported:
+		return fault.HasCode("ActionNotSupported")
+	case ErrInvalidArgVal:
+		return fault.HasCode("InvalidArgVal")
+	}
+	return false
+}
+
+// HTTPError is returned when a device answers with an HTTP error status and
+// a body which is not a SOAP fault.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+// Error implements the error interface
+func (err *HTTPError) Error() string {
+	return "onvif: unexpected HTTP status " + err.Status
+}
+
+// Is makes 401 and 403 responses match ErrNotAuthorized with errors.Is
+func (err *HTTPError) Is(target error) bool {
+	return target == ErrNotAuthorized &&
+		(err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden)
+}
+
+// IsNotAuthorized reports whether err is an authentication failure: a
+// ter:NotAuthorized SOAP fault, a fault sent with an HTTP 401, or an HTTP 401
+// or 403 without a fault. Some devices answer 403 to wrong credentials, or
+// to a user they locked out.
+func IsNotAuthorized(err error) bool {
+	return errors.Is(err, ErrNotAuthorized)
+}
+
+// IsActionNotSupported reports whether err is a ter:ActionNotSupported fault.
+func IsActionNotSupported(err error) bool {
+	return errors.Is(err, ErrActionNotSupported)
+}
+
+// IsInvalidArgVal reports whether err is a ter:InvalidArgVal fault.
+func IsInvalidArgVal(err error) bool {
+	return errors.Is(err, ErrInvalidArgVal)
+}
+
+// isDeviceError reports whether err was answered by the device, as opposed
+// to a network or decoding error.
+func isDeviceError(err error) bool {
+	var fault *SOAPFault
+	var httpErr *HTTPError
+	return errors.As(err, &fault) || errors.As(err, &httpErr)
+}
+
+// faultCode is the SOAP 1.2 Code and Subcode element
+type faultCode struct {
+	Value   string     `xml:"Value"`
+	Subcode *faultCode `xml:"Subcode"`
+}
+
+type faultInner struct {
+	Inner string `xml:",innerxml"`
+}
+
+// faultEnvelope matches both SOAP 1.2 and SOAP 1.1 faults
+type faultEnvelope struct {
+	Fault *struct {
+		Code   faultCode `xml:"Code"`
+		Reason struct {
+			Text []string `xml:"Text"`
+		} `xml:"Reason"`
+		Detail faultInner `xml:"Detail"`
+
+		FaultCode   string     `xml:"faultcode"`
+		FaultString string     `xml:"faultstring"`
+		FaultDetail faultInner `xml:"detail"`
+	} `xml:"Body>Fault"`
+}
+
+// parseSOAPFault returns the SOAP fault contained in a response body, or nil
+// if the body is not a fault.
+func parseSOAPFault(body []byte, httpStatus int) *SOAPFault {
+	if !bytes.Contains(body, []byte("Fault")) {
+		return nil
+	}
+
+	envelope := faultEnvelope{}
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Fault == nil {
+		return nil
+	}
+
+	raw := envelope.Fault
+	fault := &SOAPFault{HTTPStatus: httpStatus}
+	if raw.Code.Value != "" {
+		// SOAP 1.2
+		fault.Code = strings.TrimSpace(raw.Code.Value)
+		for subcode := raw.Code.Subcode; subcode != nil; subcode = subcode.Subcode {
+			fault.Subcodes = append(fault.Subcodes, strings.TrimSpace(subcode.Value))
+		}
+		if len(raw.Reason.Text) > 0 {
+			fault.Reason = strings.TrimSpace(raw.Reason.Text[0])
+		}
+		fault.Detail = strings.TrimSpace(raw.Detail.Inner)
+	} else {
+		// SOAP 1.1
+		fault.Code = strings.TrimSpace(raw.FaultCode)
+		fault.Reason = strings.TrimSpace(raw.FaultString)
+		fault.Detail = strings.TrimSpace(raw.FaultDetail.Inner)
+	}
+	return fault
+}
+
+// localName strips the namespace prefix of a qualified name
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
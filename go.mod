@@ -1,9 +1,8 @@
 module github.com/quocson95/go-onvif
 
-go 1.13
+go 1.15
 
 require (
 	github.com/clbanning/mxj v1.8.4
-	github.com/golang/glog v0.0.0-20210429001901-424d2337a529
 	github.com/google/uuid v1.2.0
 )
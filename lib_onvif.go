@@ -2,7 +2,6 @@ package onvif
 
 import (
 	"encoding/json"
-	"github.com/golang/glog"
 	"net"
 	"time"
 )
@@ -22,11 +21,17 @@ func DiscoveryDeviceByIp(ip string, duration int) string {
 	result := OnvifData{}
 	// Discover device on interface's network
 	result.Error = ""
-	devices, err := discoverDevices(ip, time.Duration(duration)*time.Millisecond)
+	localAddress, err := net.ResolveUDPAddr("udp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		result.Error = err.Error()
+		str, _ := json.Marshal(result)
+		return string(str)
+	}
+	devices, err := discoverDevices(localAddress, time.Duration(duration)*time.Millisecond, ProbeFilter{})
 	if err != nil {
 		result.Error = err.Error()
 	}
-	result.Data = devices
+	result.Data = devicesOf(devices)
 	str, _ := json.Marshal(result)
 	return string(str)
 }
@@ -41,32 +46,21 @@ func DiscoveryDevice(interfaceName string, duration int) string {
 		return string(str)
 	}
 
-	item, _ := itf.Addrs()
-	var ip net.IP
-	for _, addr := range item {
-		switch v := addr.(type) {
-		case *net.IPNet:
-			if !v.IP.IsLoopback() {
-				if v.IP.To4() != nil { //Verify if IP is IPV4
-					ip = v.IP
-				}
-			}
-		}
-	}
-
-	if ip == nil {
+	if len(discoveryAddresses(*itf)) == 0 {
 		result.Error = "cannot get device ip"
 		str, _ := json.Marshal(result)
 		return string(str)
 	}
 
-	// Discover device on interface's network
+	// Discover device on interface's network, over IPv4 and IPv6
 	result.Error = ""
-	devices, err := discoverDevices(ip.String(), time.Duration(duration)*time.Millisecond)
+	devices, err := discoverOnInterfaces([]net.Interface{*itf}, func(localAddress *net.UDPAddr) ([]DiscoveredDevice, error) {
+		return discoverDevices(localAddress, time.Duration(duration)*time.Millisecond, ProbeFilter{})
+	})
 	if err != nil {
 		result.Error = err.Error()
 	}
-	result.Data = devices
+	result.Data = devicesOf(devices)
 	str, _ := json.Marshal(result)
 	return string(str)
 }
@@ -94,7 +88,7 @@ func GetMediaInformation(host, username, password string) string {
 	caps, err := od.GetCapabilities()
 	if err != nil {
 		profile.LastError = "profile.onvif.getcapabilities.error"
-		glog.Warning("Get capabilities error")
+		od.logger().Log(LevelWarn, "get capabilities failed", "xaddr", od.XAddr, "error", err)
 
 		result.Error = "res.error.getcapabilities"
 		result.Data = profile
@@ -119,7 +113,7 @@ func GetMediaInformation(host, username, password string) string {
 
 	if err != nil {
 		profile.LastError = "profile.onvif.getprofiles.error"
-		glog.Warning("Get profiles error")
+		od.logger().Log(LevelWarn, "get profiles failed", "xaddr", odm.XAddr, "error", err)
 		result.Error = "res.error.getprofiles"
 		result.Data = profile
 		str, _ := json.Marshal(result)
@@ -127,12 +121,12 @@ func GetMediaInformation(host, username, password string) string {
 	}
 
 	for _, ovfprofile := range profiles {
-		glog.Infof("Get profile %s", ovfprofile.Token)
+		od.logger().Log(LevelInfo, "get profile", "token", ovfprofile.Token)
 		// Get streaming uri
 		uri, err := odm.GetStreamURI(ovfprofile.Token, "RTSP")
 
 		if err != nil {
-			glog.Warning("Get streaming error")
+			od.logger().Log(LevelWarn, "get stream uri failed", "token", ovfprofile.Token, "error", err)
 			continue
 		}
 
@@ -145,7 +139,7 @@ func GetMediaInformation(host, username, password string) string {
 
 		if err != nil {
 			profile.LastError = "profile.onvif.getsnapshot.error"
-			glog.Warningf("Get snapshot for %s error %v", ovfprofile.Token, err)
+			od.logger().Log(LevelWarn, "get snapshot uri failed", "token", ovfprofile.Token, "error", err)
 		}
 
 		profile.Streams = append(profile.Streams, Stream{
@@ -161,7 +155,7 @@ func GetMediaInformation(host, username, password string) string {
 			VideoSourceToken: ovfprofile.VideoSourceConfig.Token,
 		})
 
-		glog.Infof("Get profile %s done", ovfprofile.Token)
+		od.logger().Log(LevelInfo, "get profile done", "token", ovfprofile.Token)
 	}
 
 	profile.Authorize = true
@@ -205,10 +199,10 @@ func PtzStart(host, username, password string, x, y, z float64) string {
 	ptzXAddr := mapPtzXAddr[od.XAddr]
 	mediaXAddr := mapMediaXAddr[od.XAddr]
 	if ptzXAddr == "" || mediaXAddr == "" {
-		glog.Info("Find PTZ And Media Address")
+		od.logger().Log(LevelInfo, "finding PTZ and media addresses", "xaddr", od.XAddr)
 		caps, err := GetXAddress(od)
 		if err != nil {
-			if CheckAuthorizedError(err.Error()) {
+			if IsNotAuthorized(err) {
 				result.Error = "res.error.unauthorized"
 			} else {
 				result.Error = "res.error.getptzxaddr"
@@ -222,7 +216,7 @@ func PtzStart(host, username, password string, x, y, z float64) string {
 	// get profile
 	profileToken := mapProfile[od.XAddr]
 	if profileToken == "" {
-		glog.Info("Find Profile")
+		od.logger().Log(LevelInfo, "finding profile", "xaddr", mediaXAddr)
 		// Media device control
 		odMedia := Device{
 			XAddr:    mediaXAddr,
@@ -231,8 +225,8 @@ func PtzStart(host, username, password string, x, y, z float64) string {
 		}
 		profiles, err := odMedia.GetProfiles()
 		if err != nil {
-			glog.Info(err)
-			if CheckAuthorizedError(err.Error()) {
+			od.logger().Log(LevelWarn, "get profiles failed", "xaddr", mediaXAddr, "error", err)
+			if IsNotAuthorized(err) {
 				result.Error = "res.error.unauthorized"
 			} else {
 				result.Error = "res.error.getprofile"
@@ -243,8 +237,7 @@ func PtzStart(host, username, password string, x, y, z float64) string {
 		mapProfile[od.XAddr] = profiles[0].Token
 		profileToken = mapProfile[od.XAddr]
 	}
-	glog.Info("PTZ XAddr: ", ptzXAddr)
-	glog.Info("Profile Token: ", profileToken)
+	od.logger().Log(LevelInfo, "ptz control", "xaddr", ptzXAddr, "profile", profileToken)
 	// PTZ device control
 	odPtz := Device{
 		XAddr:    ptzXAddr,
@@ -261,8 +254,8 @@ func PtzStart(host, username, password string, x, y, z float64) string {
 		},
 	})
 	if err != nil {
-		glog.Warning("PTZ Start Error: ", err.Error())
-		if CheckAuthorizedError(err.Error()) {
+		od.logger().Log(LevelWarn, "ptz continuous move failed", "xaddr", ptzXAddr, "error", err)
+		if IsNotAuthorized(err) {
 			result.Error = "res.error.unauthorized"
 		} else {
 			result.Error = "res.error.ptzstart"
@@ -287,11 +280,11 @@ func PtzStop(host, username, password string) string {
 	ptzXAddr := mapPtzXAddr[od.XAddr]
 	mediaXAddr := mapMediaXAddr[od.XAddr]
 	if ptzXAddr == "" || mediaXAddr == "" {
-		glog.Info("Find PTZ And Media Address")
+		od.logger().Log(LevelInfo, "finding PTZ and media addresses", "xaddr", od.XAddr)
 
 		caps, err := GetXAddress(od)
 		if err != nil {
-			if CheckAuthorizedError(err.Error()) {
+			if IsNotAuthorized(err) {
 				result.Error = "res.error.unauthorized"
 			} else {
 				result.Error = "res.error.getptzxaddr"
@@ -306,7 +299,7 @@ func PtzStop(host, username, password string) string {
 	// get profile
 	profileToken := mapProfile[od.XAddr]
 	if profileToken == "" {
-		glog.Info("Find Profile")
+		od.logger().Log(LevelInfo, "finding profile", "xaddr", mediaXAddr)
 		// Media device control
 		odMedia := Device{
 			XAddr:    mediaXAddr,
@@ -315,8 +308,8 @@ func PtzStop(host, username, password string) string {
 		}
 		profiles, err := odMedia.GetProfiles()
 		if err != nil {
-			glog.Info(err)
-			if CheckAuthorizedError(err.Error()) {
+			od.logger().Log(LevelWarn, "get profiles failed", "xaddr", mediaXAddr, "error", err)
+			if IsNotAuthorized(err) {
 				result.Error = "res.error.unauthorized"
 			} else {
 				result.Error = "res.error.getprofile"
@@ -327,8 +320,7 @@ func PtzStop(host, username, password string) string {
 		mapProfile[od.XAddr] = profiles[0].Token
 		profileToken = mapProfile[od.XAddr]
 	}
-	glog.Info("PTZ XAddr: ", ptzXAddr)
-	glog.Info("Profile Token: ", profileToken)
+	od.logger().Log(LevelInfo, "ptz control", "xaddr", ptzXAddr, "profile", profileToken)
 	// PTZ device control
 	odPtz := Device{
 		XAddr:    ptzXAddr,
@@ -337,8 +329,8 @@ func PtzStop(host, username, password string) string {
 	}
 	err := odPtz.Stop(profileToken)
 	if err != nil {
-		glog.Warning("PTZ Stop Error: ", err.Error())
-		if CheckAuthorizedError(err.Error()) {
+		od.logger().Log(LevelWarn, "ptz stop failed", "xaddr", ptzXAddr, "error", err)
+		if IsNotAuthorized(err) {
 			result.Error = "res.error.unauthorized"
 		} else {
 			result.Error = "res.error.ptzstop"
@@ -363,11 +355,11 @@ func PtzGoToHome(host, username, password string) string {
 	ptzXAddr := mapPtzXAddr[od.XAddr]
 	mediaXAddr := mapMediaXAddr[od.XAddr]
 	if ptzXAddr == "" || mediaXAddr == "" {
-		glog.Info("Find PTZ And Media Address")
+		od.logger().Log(LevelInfo, "finding PTZ and media addresses", "xaddr", od.XAddr)
 
 		caps, err := GetXAddress(od)
 		if err != nil {
-			if CheckAuthorizedError(err.Error()) {
+			if IsNotAuthorized(err) {
 				result.Error = "res.error.unauthorized"
 			} else {
 				result.Error = "res.error.getptzxaddr"
@@ -382,7 +374,7 @@ func PtzGoToHome(host, username, password string) string {
 	// get profile
 	profileToken := mapProfile[od.XAddr]
 	if profileToken == "" {
-		glog.Info("Find Profile")
+		od.logger().Log(LevelInfo, "finding profile", "xaddr", mediaXAddr)
 		// Media device control
 		odMedia := Device{
 			XAddr:    mediaXAddr,
@@ -391,8 +383,8 @@ func PtzGoToHome(host, username, password string) string {
 		}
 		profiles, err := odMedia.GetProfiles()
 		if err != nil {
-			glog.Info(err)
-			if CheckAuthorizedError(err.Error()) {
+			od.logger().Log(LevelWarn, "get profiles failed", "xaddr", mediaXAddr, "error", err)
+			if IsNotAuthorized(err) {
 				result.Error = "res.error.unauthorized"
 			} else {
 				result.Error = "res.error.getprofile"
@@ -403,8 +395,7 @@ func PtzGoToHome(host, username, password string) string {
 		mapProfile[od.XAddr] = profiles[0].Token
 		profileToken = mapProfile[od.XAddr]
 	}
-	glog.Info("PTZ XAddr: ", ptzXAddr)
-	glog.Info("Profile Token: ", profileToken)
+	od.logger().Log(LevelInfo, "ptz control", "xaddr", ptzXAddr, "profile", profileToken)
 	// PTZ device control
 	odPtz := Device{
 		XAddr:    ptzXAddr,
@@ -413,8 +404,8 @@ func PtzGoToHome(host, username, password string) string {
 	}
 	err := odPtz.GotoHomePosition(profileToken)
 	if err != nil {
-		glog.Warning("PTZ Stop Error: ", err.Error())
-		if CheckAuthorizedError(err.Error()) {
+		od.logger().Log(LevelWarn, "ptz go to home failed", "xaddr", ptzXAddr, "error", err)
+		if IsNotAuthorized(err) {
 			result.Error = "res.error.unauthorized"
 		} else {
 			result.Error = "res.error.ptzstop"
@@ -0,0 +1,126 @@
+package onvif
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+const (
+	// LevelDebug is used for the SOAP envelopes and decoded results
+	LevelDebug Level = iota
+	// LevelInfo is used for the progress of multi-step operations
+	LevelInfo
+	// LevelWarn is used for failures which the operation recovers from
+	LevelWarn
+	// LevelError is used for failures which end the operation
+	LevelError
+)
+
+// String implements the fmt.Stringer interface
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "Level(" + intToString(int(level)) + ")"
+}
+
+// Logger receives the log entries of the package. The keyvals hold
+// alternating keys and values, in the style of log/slog, so that entries
+// can be forwarded to a structured logger as they are.
+//
+// SOAP envelopes are redacted before they are logged, but they still hold
+// the values sent to and by the device.
+type Logger interface {
+	Log(level Level, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc adapts a function to the Logger interface
+type LoggerFunc func(level Level, msg string, keyvals ...interface{})
+
+// Log calls f(level, msg, keyvals...)
+func (f LoggerFunc) Log(level Level, msg string, keyvals ...interface{}) {
+	f(level, msg, keyvals...)
+}
+
+// nopLogger discards every entry, it is used by clients without a Logger
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...interface{}) {}
+
+// NewStdLogger returns a Logger which prints the entries at or above
+// minLevel to out, as "LEVEL msg key=value ...". When out is nil, the
+// standard logger of the log package is used.
+func NewStdLogger(out *log.Logger, minLevel Level) Logger {
+	output := log.Print
+	if out != nil {
+		output = out.Print
+	}
+
+	return LoggerFunc(func(level Level, msg string, keyvals ...interface{}) {
+		if level < minLevel {
+			return
+		}
+
+		var line strings.Builder
+		line.WriteString(level.String())
+		line.WriteString(" ")
+		line.WriteString(msg)
+		for i := 0; i < len(keyvals); i += 2 {
+			if i+1 < len(keyvals) {
+				fmt.Fprintf(&line, " %v=%v", keyvals[i], keyvals[i+1])
+			} else {
+				fmt.Fprintf(&line, " %v", keyvals[i])
+			}
+		}
+		output(line.String())
+	})
+}
+
+// logger returns the Logger of the client. Entries of a nil client go to
+// DefaultClient, and they are discarded when it has no Logger.
+func (client *Client) logger() Logger {
+	if client == nil {
+		client = DefaultClient
+	}
+	if client.Logger == nil {
+		return nopLogger{}
+	}
+	return client.Logger
+}
+
+// logger returns the Logger of the device's client
+func (device Device) logger() Logger {
+	return device.Client.logger()
+}
+
+const redacted = "REDACTED"
+
+var (
+	// securityHeader matches the WS-Security header, whose token holds the
+	// user name, the password or its digest, and the nonce
+	securityHeader = regexp.MustCompile(`(?s)<((?:[\w.-]+:)?Security)\b[^>]*>.*?</((?:[\w.-]+:)?Security)>`)
+
+	// passwordElement matches the elements holding a password, such as the
+	// users of CreateUsers and SetUser
+	passwordElement = regexp.MustCompile(`(?s)(<(?:[\w.-]+:)?Password\b(?:[^>]*[^/>])?>).*?(</(?:[\w.-]+:)?Password>)`)
+)
+
+// RedactEnvelope returns the SOAP envelope with its WS-Security header and
+// the content of its Password elements replaced by "REDACTED". It is applied
+// to the envelopes given to the Logger and to the Trace hooks.
+func RedactEnvelope(envelope string) string {
+	envelope = securityHeader.ReplaceAllString(envelope, "<$1>"+redacted+"</$2>")
+	return passwordElement.ReplaceAllString(envelope, "${1}"+redacted+"${2}")
+}
@@ -0,0 +1,78 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRedactEnvelope(t *testing.T) {
+	envelope := `<s:Envelope><s:Header><wsse:Security s:mustUnderstand="1"><wsse:UsernameToken>` +
+		`<wsse:Username>admin</wsse:Username><wsse:Password Type="#PasswordText">s3cret</wsse:Password>` +
+		`</wsse:UsernameToken></wsse:Security></s:Header><s:Body><CreateUsers><User>` +
+		`<Username>oper</Username><Password>hunter2</Password><PasswordDigest/></User></CreateUsers></s:Body></s:Envelope>`
+
+	want := `<s:Envelope><s:Header><wsse:Security>REDACTED</wsse:Security></s:Header><s:Body><CreateUsers><User>` +
+		`<Username>oper</Username><Password>REDACTED</Password><PasswordDigest/></User></CreateUsers></s:Body></s:Envelope>`
+	if got := RedactEnvelope(envelope); got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestClientLoggerAndTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><CreateUsersResponse xmlns="http://www.onvif.org/ver10/device/wsdl"/></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	type traceKey struct{}
+	var mu sync.Mutex
+	var entries []string
+	var done []TraceResponse
+	client := NewClient()
+	client.Logger = LoggerFunc(func(level Level, msg string, keyvals ...interface{}) {
+		mu.Lock()
+		entries = append(entries, fmt.Sprint(level, msg, keyvals))
+		mu.Unlock()
+	})
+	client.Trace = &Trace{
+		RequestStart: func(ctx context.Context, request TraceRequest) context.Context {
+			return context.WithValue(ctx, traceKey{}, request.Operation)
+		},
+		RequestDone: func(ctx context.Context, request TraceRequest, response TraceResponse) {
+			if ctx.Value(traceKey{}) != request.Operation {
+				t.Errorf("context of RequestStart not passed to RequestDone")
+			}
+			if request.Operation == "CreateUsers" {
+				mu.Lock()
+				done = append(done, response)
+				mu.Unlock()
+			}
+		},
+	}
+
+	device := client.NewDevice(server.URL, "admin", "s3cret")
+	device.AuthMode = AuthUsernameTokenText
+	if err := device.CreateUsers([]User{{Username: "oper", Password: "hunter2", UserLevel: "User"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(done) != 1 || done[0].StatusCode != http.StatusOK || done[0].Err != nil {
+		t.Fatalf("unexpected traced responses %+v", done)
+	}
+	if !strings.Contains(done[0].Envelope, "CreateUsersResponse") {
+		t.Errorf("traced response envelope %q", done[0].Envelope)
+	}
+	if len(entries) == 0 {
+		t.Fatal("nothing was logged")
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, "s3cret") || strings.Contains(entry, "hunter2") {
+			t.Errorf("password leaked in log entry %s", entry)
+		}
+	}
+}
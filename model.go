@@ -2,17 +2,21 @@ package onvif
 
 // Device contains data of ONVIF camera
 type Device struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	XAddr    string `json:"xAddr"`
-	User     string `json:"user"`
-	Password string `json:"password"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	XAddr    string   `json:"xAddr"`
+	User     string   `json:"user"`
+	Password string   `json:"password"`
+	AuthMode AuthMode `json:"authMode,omitempty"`
+
+	// Client used to send requests, DefaultClient when nil
+	Client *Client `json:"-"`
 }
 
 // DeviceInformation contains information of ONVIF camera
 type DeviceInformation struct {
 	FirmwareVersion string
-	HardwareID      string
+	HardwareID      string `xml:"HardwareId"`
 	Manufacturer    string
 	Model           string
 	SerialNumber    string
@@ -23,7 +27,7 @@ type NetworkCapabilities struct {
 	DynDNS     bool
 	IPFilter   bool
 	IPVersion6 bool
-	ZeroConfig bool
+	ZeroConfig bool `xml:"ZeroConfiguration"`
 }
 
 // MediaCapabilities contains media capabilities of ONVIF camera
@@ -71,7 +75,7 @@ type MediaBounds struct {
 // MediaSourceConfig contains configuration of a media source
 type MediaSourceConfig struct {
 	Name        string
-	Token       string
+	Token       string `xml:"token,attr"`
 	SourceToken string
 	Bounds      MediaBounds
 }
@@ -91,7 +95,7 @@ type H264Configuration struct {
 
 type VideoEncoderConfig struct {
 	Name                string
-	Token               string
+	Token               string `xml:"token,attr"`
 	Encoding            string
 	Quality             float64
 	RateControl         VideoRateControl
@@ -106,7 +110,7 @@ type VideoEncoderConfig struct {
 // AudioEncoderConfig contains configuration of an audio encoder
 type AudioEncoderConfig struct {
 	Name           string
-	Token          string
+	Token          string `xml:"token,attr"`
 	Encoding       string
 	Bitrate        int
 	SampleRate     int
@@ -116,24 +120,24 @@ type AudioEncoderConfig struct {
 // PTZConfig contains configuration of a PTZ control in camera
 type PTZConfig struct {
 	Name      string
-	Token     string
+	Token     string `xml:"token,attr"`
 	NodeToken string
 }
 
 // MediaProfile contains media profile of an ONVIF camera
 type MediaProfile struct {
 	Name               string
-	Token              string
-	VideoSourceConfig  MediaSourceConfig
-	VideoEncoderConfig VideoEncoderConfig
-	AudioSourceConfig  MediaSourceConfig
-	AudioEncoderConfig AudioEncoderConfig
-	PTZConfig          PTZConfig
+	Token              string             `xml:"token,attr"`
+	VideoSourceConfig  MediaSourceConfig  `xml:"VideoSourceConfiguration"`
+	VideoEncoderConfig VideoEncoderConfig `xml:"VideoEncoderConfiguration"`
+	AudioSourceConfig  MediaSourceConfig  `xml:"AudioSourceConfiguration"`
+	AudioEncoderConfig AudioEncoderConfig `xml:"AudioEncoderConfiguration"`
+	PTZConfig          PTZConfig          `xml:"PTZConfiguration"`
 }
 
 // MediaURI contains streaming URI of an ONVIF camera
 type MediaURI struct {
-	URI                 string
+	URI                 string `xml:"Uri"`
 	Timeout             string
 	InvalidAfterConnect bool
 	InvalidAfterReboot  bool
@@ -235,7 +239,7 @@ type IPv4NetworkInterface struct {
 }
 
 type NetworkInterface struct {
-	Token   string
+	Token   string `xml:"token,attr"`
 	Enabled bool
 	Info    NetworkInterfaceInfo
 	Link    NetworkInterfaceLink
@@ -275,7 +279,7 @@ type RelayOutputSettings struct {
 }
 
 type RelayOutput struct {
-	Token      string
+	Token      string `xml:"token,attr"`
 	Properties RelayOutputSettings
 }
 
@@ -288,37 +292,37 @@ type NetworkZeroConfiguration struct {
 
 // Service Device
 type DeviceSecurityCapabilitiesService struct {
-	RemoteUserHandling   bool
-	Dot1X                bool
-	AccesssPolicyConfig  bool
-	OnboardKeyGeneration bool
-	HttpDigest           bool
-	X509Token            bool
-	DefaultAccessPolicy  bool
-	RELToken             bool
-	KerberosToken        bool
-	TLS12                bool
-	TLS11                bool
-	TLS10                bool
-	UsernameToken        bool
-	SAMLToken            bool
+	RemoteUserHandling   bool `xml:"RemoteUserHandling,attr"`
+	Dot1X                bool `xml:"Dot1X,attr"`
+	AccesssPolicyConfig  bool `xml:"AccessPolicyConfig,attr"`
+	OnboardKeyGeneration bool `xml:"OnboardKeyGeneration,attr"`
+	HttpDigest           bool `xml:"HttpDigest,attr"`
+	X509Token            bool `xml:"X.509Token,attr"`
+	DefaultAccessPolicy  bool `xml:"DefaultAccessPolicy,attr"`
+	RELToken             bool `xml:"RELToken,attr"`
+	KerberosToken        bool `xml:"KerberosToken,attr"`
+	TLS12                bool `xml:"TLS1.2,attr"`
+	TLS11                bool `xml:"TLS1.1,attr"`
+	TLS10                bool `xml:"TLS1.0,attr"`
+	UsernameToken        bool `xml:"UsernameToken,attr"`
+	SAMLToken            bool `xml:"SAMLToken,attr"`
 }
 
 type DeviceSystemCapabilitiesService struct {
-	DiscoveryBye     bool
-	DiscoveryResolve bool
-	FirmwareUpgrade  bool
-	SystemLogging    bool
-	SystemBackup     bool
-	RemoteDiscovery  bool
+	DiscoveryBye     bool `xml:"DiscoveryBye,attr"`
+	DiscoveryResolve bool `xml:"DiscoveryResolve,attr"`
+	FirmwareUpgrade  bool `xml:"FirmwareUpgrade,attr"`
+	SystemLogging    bool `xml:"SystemLogging,attr"`
+	SystemBackup     bool `xml:"SystemBackup,attr"`
+	RemoteDiscovery  bool `xml:"RemoteDiscovery,attr"`
 }
 
 type DeviceNetworkCapabilitiesService struct {
-	NTP               int
-	DynDNS            bool
-	IPVersion6        bool
-	ZeroConfiguration bool
-	IPFilter          bool
+	NTP               int  `xml:"NTP,attr"`
+	DynDNS            bool `xml:"DynDNS,attr"`
+	IPVersion6        bool `xml:"IPVersion6,attr"`
+	ZeroConfiguration bool `xml:"ZeroConfiguration,attr"`
+	IPFilter          bool `xml:"IPFilter,attr"`
 }
 
 type DeviceCapabilitiesService struct {
@@ -329,45 +333,45 @@ type DeviceCapabilitiesService struct {
 
 // Service Media
 type MediaProfileCapabilitiesService struct {
-	MaximumNumberOfProfiles int
+	MaximumNumberOfProfiles int `xml:"MaximumNumberOfProfiles,attr"`
 }
 type MediaStreamingCapabilitiesService struct {
-	RTP_RTSP_TCP        bool
-	RTP_TCP             bool
-	RTPMulticast        bool
-	NoRTSPStreaming     bool
-	NonAggregateControl bool
+	RTP_RTSP_TCP        bool `xml:"RTP_RTSP_TCP,attr"`
+	RTP_TCP             bool `xml:"RTP_TCP,attr"`
+	RTPMulticast        bool `xml:"RTPMulticast,attr"`
+	NoRTSPStreaming     bool `xml:"NoRTSPStreaming,attr"`
+	NonAggregateControl bool `xml:"NonAggregateControl,attr"`
 }
 
 type MediaCapabilitiesService struct {
-	OSD                   bool
-	VideoSourceMode       bool
-	Rotation              bool
-	SnapshotUri           bool
+	OSD                   bool `xml:"OSD,attr"`
+	VideoSourceMode       bool `xml:"VideoSourceMode,attr"`
+	Rotation              bool `xml:"Rotation,attr"`
+	SnapshotUri           bool `xml:"SnapshotUri,attr"`
 	ProfileCapabilities   MediaProfileCapabilitiesService
 	StreamingCapabilities MediaStreamingCapabilitiesService
 }
 
 // Service Events
 type EventsCapabilitiesService struct {
-	MaxNotificationProducers                      int
-	WSPausableSubscriptionManagerInterfaceSupport bool
-	WSPullPointSupport                            bool
-	WSSubscriptionPolicySupport                   bool
-	PersistentNotificationStorage                 bool
-	MaxPullPoints                                 int
+	MaxNotificationProducers                      int  `xml:"MaxNotificationProducers,attr"`
+	WSPausableSubscriptionManagerInterfaceSupport bool `xml:"WSPausableSubscriptionManagerInterfaceSupport,attr"`
+	WSPullPointSupport                            bool `xml:"WSPullPointSupport,attr"`
+	WSSubscriptionPolicySupport                   bool `xml:"WSSubscriptionPolicySupport,attr"`
+	PersistentNotificationStorage                 bool `xml:"PersistentNotificationStorage,attr"`
+	MaxPullPoints                                 int  `xml:"MaxPullPoints,attr"`
 }
 
 // Service Imaging
 type ImagingCapabilitiesService struct {
-	ImageStabilization bool
+	ImageStabilization bool `xml:"ImageStabilization,attr"`
 }
 
 // Service PTZ
 type PTZCapabilitiesService struct {
-	GetCompatibleConfigurations bool
-	Reverse                     bool
-	EFlip                       bool
+	GetCompatibleConfigurations bool `xml:"GetCompatibleConfigurations,attr"`
+	Reverse                     bool `xml:"Reverse,attr"`
+	EFlip                       bool `xml:"EFlip,attr"`
 }
 
 type OnvifVersion struct {
@@ -383,7 +387,7 @@ type CapabilitiesService struct {
 type Service struct {
 	Namespace    string
 	XAddr        string
-	Capabilities CapabilitiesService
+	Capabilities CapabilitiesService `xml:"-"`
 	Version      OnvifVersion
 }
 
@@ -415,7 +419,7 @@ type GuaranteedNumberOfVideoEncoderInstances struct {
 
 // VideoSource
 type VideoSource struct {
-	Token      string
+	Token      string `xml:"token,attr"`
 	Framerate  float64
 	Resolution MediaBounds
 	Imaging    ImagingSettings
@@ -481,17 +485,17 @@ type WhiteBalance struct {
 
 // Video Source Configuration
 type VideoSourceConfiguration struct {
-	Token       string
+	Token       string `xml:"token,attr"`
 	Name        string
 	SourceToken string
 	Bounds      IntRectangle
 }
 
 type IntRectangle struct {
-	X      int
-	Y      int
-	Width  int
-	Height int
+	X      int `xml:"x,attr"`
+	Y      int `xml:"y,attr"`
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
 }
 
 type IntRectangleRange struct {
@@ -502,7 +506,7 @@ type IntRectangleRange struct {
 }
 
 type VideoSourceConfigurationOption struct {
-	MaximumNumberOfProfiles    int
+	MaximumNumberOfProfiles    int `xml:"MaximumNumberOfProfiles,attr"`
 	BoundsRange                IntRectangleRange
 	VideoSourceTokensAvailable string
 }
@@ -515,7 +519,7 @@ type Multicast struct {
 }
 
 type MetadataConfiguration struct {
-	Token          string
+	Token          string `xml:"token,attr"`
 	Name           string
 	SessionTimeout string
 	Multicast      Multicast
@@ -529,17 +533,17 @@ type PTZStatusFilterOptions struct {
 }
 
 type MetadataConfigurationOptions struct {
-	GeoLocation            bool
+	GeoLocation            bool `xml:"GeoLocation,attr"`
 	PTZStatusFilterOptions PTZStatusFilterOptions
 }
 
 type AudioSource struct {
-	Token    string
-	Channels int //1: mono, 2: stereo
+	Token    string `xml:"token,attr"`
+	Channels int    //1: mono, 2: stereo
 }
 
 type AudioSourceConfiguration struct {
-	Token       string
+	Token       string `xml:"token,attr"`
 	Name        string
 	SourceToken string
 }
@@ -578,9 +582,9 @@ type PTZSpaces struct {
 }
 
 type PTZNode struct {
-	Token                  string
-	FixedHomePosition      bool
-	GeoMove                bool
+	Token                  string `xml:"token,attr"`
+	FixedHomePosition      bool   `xml:"FixedHomePosition,attr"`
+	GeoMove                bool   `xml:"GeoMove,attr"`
 	Name                   string
 	SupportedPTZSpaces     PTZSpaces
 	MaximumNumberOfPresets int
@@ -588,14 +592,14 @@ type PTZNode struct {
 }
 
 type Vector2D struct {
-	Space string  `json:"space"`
-	X     float64 `json:"x"`
-	Y     float64 `json:"y"`
+	Space string  `json:"space" xml:"space,attr"`
+	X     float64 `json:"x" xml:"x,attr"`
+	Y     float64 `json:"y" xml:"y,attr"`
 }
 
 type Vector1D struct {
-	Space string  `json:"space"`
-	X     float64 `json:"x"`
+	Space string  `json:"space" xml:"space,attr"`
+	X     float64 `json:"x" xml:"x,attr"`
 }
 
 type PTZVector struct {
@@ -612,11 +616,11 @@ type ZoomLimits struct {
 }
 
 type PTZConfiguration struct {
-	Token                                  string
+	Token                                  string `xml:"token,attr"`
 	Name                                   string
-	MoveRamp                               int
-	PresetRamp                             int
-	PresetTourRamp                         int
+	MoveRamp                               int `xml:"MoveRamp,attr"`
+	PresetRamp                             int `xml:"PresetRamp,attr"`
+	PresetTourRamp                         int `xml:"PresetTourRamp,attr"`
 	NodeToken                              string
 	DefaultAbsolutePantTiltPositionSpace   string
 	DefaultAbsoluteZoomPositionSpace       string
@@ -662,7 +666,7 @@ type PTZStatus struct {
 }
 
 type PTZPreset struct {
-	Token       string
+	Token       string `xml:"token,attr"`
 	Name        string
 	PTZPosition PTZVector
 }
@@ -678,8 +682,8 @@ type CreatePullPointSubscriptionResponse struct {
 }
 
 type MessageData struct {
-	Name  string
-	Value string
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
 }
 
 type NotificationMessage struct {
@@ -687,6 +691,9 @@ type NotificationMessage struct {
 	UtcTime string
 	Data    []MessageData
 	Source  []MessageData
+
+	// PropertyOperation is set for the messages of property topics
+	PropertyOperation PropertyOperation
 }
 
 type Point struct {
@@ -695,9 +702,9 @@ type Point struct {
 }
 
 type Mask struct {
-	Token              string
+	Token              string `xml:"token,attr"`
 	ConfigurationToken string
-	Polygon            []Point
+	Polygon            []Point `xml:"Polygon>Point"`
 	Type               string
 	Enabled            bool
 }
@@ -775,8 +782,8 @@ type RecordingSummary struct {
 
 type MediaAttributes struct {
 	RecordingToken string
-	From           string
-	Until          string
+	From           string `xml:"From,attr"`
+	Until          string `xml:"Until,attr"`
 }
 
 type Track struct {
@@ -0,0 +1,130 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxNotifySize limits the size of the Notify messages read by a
+// NotificationConsumer
+const maxNotifySize = 1 << 20
+
+// NotificationConsumer receives the WS-BaseNotification Notify messages
+// posted by devices. It is an http.Handler, to be served at an address the
+// devices can reach, and routes the messages to the EventStream of their
+// subscription.
+type NotificationConsumer struct {
+	mu      sync.Mutex
+	streams map[string]*EventStream
+}
+
+// NewNotificationConsumer returns a consumer without subscriptions
+func NewNotificationConsumer() *NotificationConsumer {
+	return &NotificationConsumer{streams: make(map[string]*EventStream)}
+}
+
+// Subscribe subscribes to the events of device, which posts them to the
+// consumer at baseURL, the URL at which the consumer is served as seen from
+// the device, followed by an identifier of the subscription. The messages
+// are received from the returned stream, which renews the subscription
+// until it is closed.
+func (consumer *NotificationConsumer) Subscribe(device Device, baseURL string, options EventStreamOptions) (*EventStream, error) {
+	return consumer.SubscribeContext(context.Background(), device, baseURL, options)
+}
+
+// SubscribeContext is like Subscribe but uses ctx for the request.
+func (consumer *NotificationConsumer) SubscribeContext(ctx context.Context, device Device, baseURL string, options EventStreamOptions) (*EventStream, error) {
+	stream := newEventStream(device, options)
+	stream.consumer = consumer
+	stream.id = uuid.New().String()
+	stream.consumerURL = strings.TrimSuffix(baseURL, "/") + "/" + stream.id
+
+	// The device may post messages as soon as it is subscribed
+	consumer.mu.Lock()
+	consumer.streams[stream.id] = stream
+	consumer.mu.Unlock()
+
+	if err := stream.subscribe(ctx); err != nil {
+		consumer.remove(stream.id)
+		stream.cancel()
+		return nil, err
+	}
+
+	stream.start()
+	return stream, nil
+}
+
+// ServeHTTP implements the http.Handler interface
+func (consumer *NotificationConsumer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stream := consumer.lookup(path.Base(r.URL.Path))
+	if stream == nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer stream.deliveries.Done()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxNotifySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var notify struct {
+		XMLName             xml.Name                 `xml:"http://docs.oasis-open.org/wsn/b-2 Notify"`
+		NotificationMessage []notificationMessageXML `xml:"NotificationMessage"`
+	}
+	if err := decodeResponse(body, &notify); err != nil {
+		stream.device.logger().Log(LevelWarn, "invalid notify message", "xaddr", stream.device.XAddr, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, notificationMessage := range notify.NotificationMessage {
+		select {
+		case stream.messages <- notificationMessage.message():
+		case <-stream.ctx.Done():
+			http.Error(w, "subscription closed", http.StatusGone)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	// Notify is a one-way operation
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// lookup returns the stream with the given identifier, and counts a
+// delivery to it which must be marked done. It returns nil when there is no
+// such stream.
+func (consumer *NotificationConsumer) lookup(id string) *EventStream {
+	consumer.mu.Lock()
+	defer consumer.mu.Unlock()
+
+	stream := consumer.streams[id]
+	if stream != nil {
+		stream.deliveries.Add(1)
+	}
+	return stream
+}
+
+// remove removes the stream with the given identifier
+func (consumer *NotificationConsumer) remove(id string) {
+	consumer.mu.Lock()
+	delete(consumer.streams, id)
+	consumer.mu.Unlock()
+}
@@ -0,0 +1,110 @@
+package onvif
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNotify = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:tns1="http://www.onvif.org/ver10/topics">
+	<env:Header><wsa:Action xmlns:wsa="http://www.w3.org/2005/08/addressing">http://docs.oasis-open.org/wsn/bw-2/NotificationConsumer/Notify</wsa:Action></env:Header>
+	<env:Body>
+		<wsnt:Notify>
+			<wsnt:NotificationMessage>
+				<wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:VideoSource/MotionAlarm</wsnt:Topic>
+				<wsnt:Message>
+					<tt:Message UtcTime="2020-01-02T03:04:05Z">
+						<tt:Source><tt:SimpleItem Name="Source" Value="VideoSource_1"/></tt:Source>
+						<tt:Data><tt:SimpleItem Name="State" Value="true"/></tt:Data>
+					</tt:Message>
+				</wsnt:Message>
+			</wsnt:NotificationMessage>
+		</wsnt:Notify>
+	</env:Body>
+</env:Envelope>`
+
+func TestNotificationConsumer(t *testing.T) {
+	service := &fakeEventService{lifetime: time.Hour, subscriptions: make(map[string]bool)}
+	device := httptest.NewServer(service)
+	defer device.Close()
+
+	consumer := NewNotificationConsumer()
+	server := httptest.NewServer(consumer)
+	defer server.Close()
+
+	stream, err := consumer.Subscribe(Device{XAddr: device.URL + "/onvif/events"}, server.URL+"/events/", EventStreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(service.consumers) != 1 || !strings.HasPrefix(service.consumers[0], server.URL+"/events/") {
+		t.Fatalf("device posts to %q", service.consumers)
+	}
+	consumerURL := service.consumers[0]
+
+	posted := make(chan int, 1)
+	go func() {
+		response, err := http.Post(consumerURL, "application/soap+xml", strings.NewReader(testNotify))
+		if err != nil {
+			t.Error(err)
+			posted <- 0
+			return
+		}
+		response.Body.Close()
+		posted <- response.StatusCode
+	}()
+
+	select {
+	case message := <-stream.Messages():
+		want := NotificationMessage{
+			Topic:   "tns1:VideoSource/MotionAlarm",
+			UtcTime: "2020-01-02T03:04:05Z",
+			Source:  []MessageData{{Name: "Source", Value: "VideoSource_1"}},
+			Data:    []MessageData{{Name: "State", Value: "true"}},
+		}
+		if message.Topic != want.Topic || message.UtcTime != want.UtcTime || message.Data[0] != want.Data[0] || message.Source[0] != want.Source[0] {
+			t.Errorf("got %+v, want %+v", message, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	if status := <-posted; status != http.StatusAccepted {
+		t.Errorf("notify answered with status %d", status)
+	}
+
+	for _, request := range []struct {
+		method, url, body string
+		want              int
+	}{
+		{http.MethodGet, consumerURL, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, server.URL + "/events/unknown", testNotify, http.StatusNotFound},
+		{http.MethodPost, consumerURL, "garbage", http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest(request.method, request.url, strings.NewReader(request.body))
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != request.want {
+			t.Errorf("%s %s answered with status %d, want %d", request.method, request.url, response.StatusCode, request.want)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.unsubscribed) != 1 || service.unsubscribed[0] != "sub1" {
+		t.Errorf("unsubscribed %q, want sub1", service.unsubscribed)
+	}
+	response, err := http.Post(consumerURL, "application/soap+xml", strings.NewReader(testNotify))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("closed subscription answered with status %d", response.StatusCode)
+	}
+}
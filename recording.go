@@ -1,20 +1,29 @@
 package onvif
 
-import "github.com/golang/glog"
+import (
+	"context"
+)
 
 func (device Device) GetRecordingConfiguration(recordingToken string) (interface{}, error) {
+	return device.GetRecordingConfigurationContext(context.Background(), recordingToken)
+}
+
+// GetRecordingConfigurationContext is like GetRecordingConfiguration but uses ctx for the request.
+func (device Device) GetRecordingConfigurationContext(ctx context.Context, recordingToken string) (interface{}, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<GetRecordingConfiguration xmlns="http://www.onvif.org/ver10/recording/wsdl">
-						<RecordingToken>` + recordingToken + `</RecordingToken>
+						<RecordingToken>` + xmlEscape(recordingToken) + `</RecordingToken>
 					</GetRecordingConfiguration>`,
 	}
 
 	var result interface{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
+	response, err := soap.SendRequestContext(ctx, device.XAddr)
 	if err != nil {
 		return result, err
 	}
@@ -24,6 +33,6 @@ func (device Device) GetRecordingConfiguration(recordingToken string) (interface
 	if err != nil {
 		return result, err
 	}
-	glog.Infof("Data %v", data)
+	device.logger().Log(LevelDebug, "decoded response", "operation", "GetRecordingConfiguration", "result", data)
 	return result, nil
 }
@@ -1,18 +1,28 @@
 package onvif
 
-import "github.com/golang/glog"
+import (
+	"context"
+	"encoding/xml"
+)
 
 func (device Device) GetReplayConfiguration() (interface{}, error) {
+	return device.GetReplayConfigurationContext(context.Background())
+}
+
+// GetReplayConfigurationContext is like GetReplayConfiguration but uses ctx for the request.
+func (device Device) GetReplayConfigurationContext(ctx context.Context) (interface{}, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetReplayConfiguration xmlns="http://www.onvif.org/ver10/replay/wsdl"/>`,
 	}
 
 	var result interface{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
+	response, err := soap.SendRequestContext(ctx, device.XAddr)
 	if err != nil {
 		return result, err
 	}
@@ -22,21 +32,28 @@ func (device Device) GetReplayConfiguration() (interface{}, error) {
 	if err != nil {
 		return result, err
 	}
-	glog.Infof("Data %v", data)
+	device.logger().Log(LevelDebug, "decoded response", "operation", "GetReplayConfiguration", "result", data)
 	return result, nil
 }
 
 func (device Device) GetReplayServiceCapabilities() (interface{}, error) {
+	return device.GetReplayServiceCapabilitiesContext(context.Background())
+}
+
+// GetReplayServiceCapabilitiesContext is like GetReplayServiceCapabilities but uses ctx for the request.
+func (device Device) GetReplayServiceCapabilitiesContext(ctx context.Context) (interface{}, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetServiceCapabilities xmlns="http://www.onvif.org/ver10/replay/wsdl"/>`,
 	}
 
 	var result interface{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
+	response, err := soap.SendRequestContext(ctx, device.XAddr)
 	if err != nil {
 		return result, err
 	}
@@ -46,17 +63,24 @@ func (device Device) GetReplayServiceCapabilities() (interface{}, error) {
 	if err != nil {
 		return result, err
 	}
-	glog.Infof("Data %v", data)
+	device.logger().Log(LevelDebug, "decoded response", "operation", "GetReplayServiceCapabilities", "result", data)
 	return result, nil
 }
 
 func (device Device) GetReplayUri(recordingToken string) (string, error) {
+	return device.GetReplayUriContext(context.Background(), recordingToken)
+}
+
+// GetReplayUriContext is like GetReplayUri but uses ctx for the request.
+func (device Device) GetReplayUriContext(ctx context.Context, recordingToken string) (string, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<GetReplayUri xmlns="http://www.onvif.org/ver10/replay/wsdl">
-						<RecordingToken>` + recordingToken + `</RecordingToken>		
+						<RecordingToken>` + xmlEscape(recordingToken) + `</RecordingToken>		
 						<StreamSetup>
 							<Stream xmlns="http://www.onvif.org/ver10/schema">RTP-Unicast</Stream>
 							<Transport xmlns="http://www.onvif.org/ver10/schema">
@@ -66,17 +90,14 @@ func (device Device) GetReplayUri(recordingToken string) (string, error) {
 					</GetReplayUri>`,
 	}
 
-	var result = ""
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/replay/wsdl GetReplayUriResponse"`
+		Uri     string
 	}
-
-	// parse response
-	data, err := response.ValueForPath("Envelope.Body.GetReplayUriResponse.Uri")
-	if err != nil {
-		return result, err
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return "", err
 	}
-	return interfaceToString(data), nil
+
+	return response.Uri, nil
 }
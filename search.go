@@ -1,242 +1,193 @@
 package onvif
 
+import (
+	"context"
+	"encoding/xml"
+)
+
 func (device Device) GetRecordingSummary() ([]RecordingSummary, error) {
+	return device.GetRecordingSummaryContext(context.Background())
+}
+
+// GetRecordingSummaryContext is like GetRecordingSummary but uses ctx for the request.
+func (device Device) GetRecordingSummaryContext(ctx context.Context) ([]RecordingSummary, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body:     `<GetRecordingSummary xmlns="http://www.onvif.org/ver10/search/wsdl"/>`,
 	}
 
 	result := make([]RecordingSummary, 0)
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName xml.Name `xml:"http://www.onvif.org/ver10/search/wsdl GetRecordingSummaryResponse"`
+		Summary []RecordingSummary
 	}
-
-	// parse response
-	summaries, err := response.ValuesForPath("Envelope.Body.GetRecordingSummaryResponse.Summary")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
 
-	for _, summaryIf := range summaries {
-		if summary, ok := summaryIf.(map[string]interface{}); ok {
-			recordingSummary := RecordingSummary{}
-			recordingSummary.DataFrom = interfaceToString(summary["DataFrom"])
-			recordingSummary.DataUntil = interfaceToString(summary["DataUntil"])
-			recordingSummary.NumberRecordings = interfaceToInt(summary["NumberRecordings"])
-			result = append(result, recordingSummary)
-		}
-	}
-
+	result = append(result, response.Summary...)
 	return result, nil
 }
 
 func (device Device) GetMediaAttributes(time string) ([]MediaAttributes, error) {
+	return device.GetMediaAttributesContext(context.Background(), time)
+}
+
+// GetMediaAttributesContext is like GetMediaAttributes but uses ctx for the request.
+func (device Device) GetMediaAttributesContext(ctx context.Context, time string) ([]MediaAttributes, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<GetMediaAttributes xmlns="http://www.onvif.org/ver10/search/wsdl">
-					<Time>` + time + `</Time>					
+					<Time>` + xmlEscape(time) + `</Time>					
 			   </GetMediaAttributes>`,
 	}
 
 	result := make([]MediaAttributes, 0)
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName         xml.Name `xml:"http://www.onvif.org/ver10/search/wsdl GetMediaAttributesResponse"`
+		MediaAttributes []MediaAttributes
 	}
-
-	// parse response
-	mediaAttributesIfs, err := response.ValuesForPath("Envelope.Body.GetMediaAttributesResponse.MediaAttributes")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
-	for _, mediaAttributesIf := range mediaAttributesIfs {
-		if mediaAttributes, ok := mediaAttributesIf.(map[string]interface{}); ok {
-			ma := MediaAttributes{}
-			ma.RecordingToken = interfaceToString(mediaAttributes["RecordingToken"])
-			ma.From = interfaceToString(mediaAttributes["From"])
-			ma.Until = interfaceToString(mediaAttributes["Until"])
-			result = append(result, ma)
-		}
-	}
+
+	result = append(result, response.MediaAttributes...)
 	return result, nil
 }
 
 func (device Device) FindRecordings() (string, error) {
+	return device.FindRecordingsContext(context.Background())
+}
+
+// FindRecordingsContext is like FindRecordings but uses ctx for the request.
+func (device Device) FindRecordingsContext(ctx context.Context) (string, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<FindRecordings xmlns="http://www.onvif.org/ver10/search/wsdl">
 					</FindRecordings>`,
 	}
 
-	var result = ""
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName     xml.Name `xml:"http://www.onvif.org/ver10/search/wsdl FindRecordingsResponse"`
+		SearchToken string
 	}
-
-	// parse response
-	data, err := response.ValueForPath("Envelope.Body.FindRecordingsResponse.SearchToken")
-	if err != nil {
-		return result, err
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return "", err
 	}
-	return interfaceToString(data), nil
+
+	return response.SearchToken, nil
 }
 
 func (device Device) GetRecordingSearchResults(searchToken string) (ResultList, error) {
+	return device.GetRecordingSearchResultsContext(context.Background(), searchToken)
+}
+
+// GetRecordingSearchResultsContext is like GetRecordingSearchResults but uses ctx for the request.
+func (device Device) GetRecordingSearchResultsContext(ctx context.Context, searchToken string) (ResultList, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<GetRecordingSearchResults xmlns="http://www.onvif.org/ver10/search/wsdl">
-					<SearchToken>` + searchToken + `</SearchToken>
+					<SearchToken>` + xmlEscape(searchToken) + `</SearchToken>
 				</GetRecordingSearchResults>`,
 	}
 
 	result := ResultList{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName    xml.Name `xml:"http://www.onvif.org/ver10/search/wsdl GetRecordingSearchResultsResponse"`
+		ResultList ResultList
 	}
-
-	// parse response
-	resultListIf, err := response.ValueForPath("Envelope.Body.GetRecordingSearchResultsResponse.ResultList")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
-	if resultList, ok := resultListIf.(map[string]interface{}); ok {
-		result.SearchState = interfaceToString(resultList["SearchState"])
-		result.RecordingInformation = make([]RecordingInformation, 0)
-		if recordingInformationList, ok := resultList["RecordingInformation"].(map[string]interface{}); ok {
-			recordingInformation := RecordingInformation{}
-			recordingInformation.RecordingToken = interfaceToString(recordingInformationList["RecordingToken"])
-			recordingInformation.EarliestRecording = interfaceToString(recordingInformationList["EarliestRecording"])
-			recordingInformation.LatestRecording = interfaceToString(recordingInformationList["LatestRecording"])
-			recordingInformation.RecordingStatus = interfaceToString(recordingInformationList["RecordingStatus"])
-			recordingInformation.Track = make([]Track, 0)
-			if listTrack, ok := recordingInformationList["Track"].([]interface{}); ok {
-				for _, t := range listTrack {
-					if track, ok := t.(map[string]interface{}); ok {
-						recordingInformation.Track = append(recordingInformation.Track, Track{
-							TrackToken:  interfaceToString(track["TrackToken"]),
-							TrackType:   interfaceToString(track["TrackType"]),
-							Description: interfaceToString(track["Description"]),
-							DataFrom:    interfaceToString(track["DataFrom"]),
-							DataTo:      interfaceToString(track["DataTo"]),
-						})
-					}
-				}
-			} else {
-				if track, ok := recordingInformationList["Track"].(map[string]interface{}); ok {
-					recordingInformation.Track = append(recordingInformation.Track, Track{
-						TrackToken:  interfaceToString(track["TrackToken"]),
-						TrackType:   interfaceToString(track["TrackType"]),
-						Description: interfaceToString(track["Description"]),
-						DataFrom:    interfaceToString(track["DataFrom"]),
-						DataTo:      interfaceToString(track["DataTo"]),
-					})
-				}
-			}
-			result.RecordingInformation = append(result.RecordingInformation, recordingInformation)
-		}
-	}
 
+	result = response.ResultList
+	result.RecordingInformation = append(make([]RecordingInformation, 0), result.RecordingInformation...)
+	for i := range result.RecordingInformation {
+		result.RecordingInformation[i].Track = append(make([]Track, 0), result.RecordingInformation[i].Track...)
+	}
 	return result, nil
 }
 
 func (device Device) FindEvents(startPoint string) (string, error) {
+	return device.FindEventsContext(context.Background(), startPoint)
+}
+
+// FindEventsContext is like FindEvents but uses ctx for the request.
+func (device Device) FindEventsContext(ctx context.Context, startPoint string) (string, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<FindEvents xmlns="http://www.onvif.org/ver10/search/wsdl">
-					<StartPoint>` + startPoint + `</StartPoint>
+					<StartPoint>` + xmlEscape(startPoint) + `</StartPoint>
 			   </FindEvents>`,
 	}
 
-	var result = ""
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName     xml.Name `xml:"http://www.onvif.org/ver10/search/wsdl FindEventsResponse"`
+		SearchToken string
 	}
-
-	// parse response
-	data, err := response.ValueForPath("Envelope.Body.FindRecordingsResponse.SearchToken")
-	if err != nil {
-		return result, err
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
+		return "", err
 	}
-	return interfaceToString(data), nil
+
+	return response.SearchToken, nil
 }
 
 func (device Device) GetEventSearchResults(searchToken string) (ResultList, error) {
+	return device.GetEventSearchResultsContext(context.Background(), searchToken)
+}
+
+// GetEventSearchResultsContext is like GetEventSearchResults but uses ctx for the request.
+func (device Device) GetEventSearchResultsContext(ctx context.Context, searchToken string) (ResultList, error) {
 	// create soap
 	soap := SOAP{
 		User:     device.User,
 		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
 		Body: `<GetEventSearchResults xmlns="http://www.onvif.org/ver10/search/wsdl">
-					<SearchToken>` + searchToken + `</SearchToken>
+					<SearchToken>` + xmlEscape(searchToken) + `</SearchToken>
 				</GetEventSearchResults>`,
 	}
 
 	result := ResultList{}
 	// send request
-	response, err := soap.SendRequest(device.XAddr)
-	if err != nil {
-		return result, err
+	var response struct {
+		XMLName    xml.Name `xml:"http://www.onvif.org/ver10/search/wsdl GetEventSearchResultsResponse"`
+		ResultList ResultList
 	}
-
-	// parse response
-	resultListIf, err := response.ValueForPath("Envelope.Body.GetEventSearchResultsResponse.ResultList")
-	if err != nil {
+	if err := soap.CallContext(ctx, device.XAddr, &response); err != nil {
 		return result, err
 	}
-	if resultList, ok := resultListIf.(map[string]interface{}); ok {
-		result.SearchState = interfaceToString(resultList["SearchState"])
-		result.RecordingInformation = make([]RecordingInformation, 0)
-		if recordingInformationList, ok := resultList["RecordingInformation"].(map[string]interface{}); ok {
-			recordingInformation := RecordingInformation{}
-			recordingInformation.RecordingToken = interfaceToString(recordingInformationList["RecordingToken"])
-			recordingInformation.EarliestRecording = interfaceToString(recordingInformationList["EarliestRecording"])
-			recordingInformation.LatestRecording = interfaceToString(recordingInformationList["LatestRecording"])
-			recordingInformation.RecordingStatus = interfaceToString(recordingInformationList["RecordingStatus"])
-			recordingInformation.Track = make([]Track, 0)
-			if listTrack, ok := recordingInformationList["Track"].([]interface{}); ok {
-				for _, t := range listTrack {
-					if track, ok := t.(map[string]interface{}); ok {
-						recordingInformation.Track = append(recordingInformation.Track, Track{
-							TrackToken:  interfaceToString(track["TrackToken"]),
-							TrackType:   interfaceToString(track["TrackType"]),
-							Description: interfaceToString(track["Description"]),
-							DataFrom:    interfaceToString(track["DataFrom"]),
-							DataTo:      interfaceToString(track["DataTo"]),
-						})
-					}
-				}
-			} else {
-				if track, ok := recordingInformationList["Track"].(map[string]interface{}); ok {
-					recordingInformation.Track = append(recordingInformation.Track, Track{
-						TrackToken:  interfaceToString(track["TrackToken"]),
-						TrackType:   interfaceToString(track["TrackType"]),
-						Description: interfaceToString(track["Description"]),
-						DataFrom:    interfaceToString(track["DataFrom"]),
-						DataTo:      interfaceToString(track["DataTo"]),
-					})
-				}
-			}
-			result.RecordingInformation = append(result.RecordingInformation, recordingInformation)
-		}
-	}
 
+	result = response.ResultList
+	result.RecordingInformation = append(make([]RecordingInformation, 0), result.RecordingInformation...)
+	for i := range result.RecordingInformation {
+		result.RecordingInformation[i].Track = append(make([]Track, 0), result.RecordingInformation[i].Track...)
+	}
 	return result, nil
 }
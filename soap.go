@@ -2,10 +2,9 @@ package onvif
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"encoding/base64"
-	"errors"
-	"github.com/quocson95/go-onvif/digest"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -13,8 +12,8 @@ import (
 	"time"
 
 	"github.com/clbanning/mxj"
-	"github.com/golang/glog"
 	"github.com/google/uuid"
+	"github.com/quocson95/go-onvif/digest"
 )
 
 // SOAP contains data for SOAP request
@@ -25,70 +24,197 @@ type SOAP struct {
 	Password string
 	TokenAge time.Duration
 	Action   string
+	// Headers are additional elements of the SOAP header, such as the
+	// WS-Addressing MessageID. Their prefixes are declared with XMLNs.
+	Headers  []string
 	NoDebug  bool
+	Client   *Client
+	AuthMode AuthMode
+
+	// offset between the device clock and the local clock
+	clockOffset time.Duration
+
+	// deviceXAddr is the address of the device service, which tells the
+	// device clock, when the request is sent to another address such as a
+	// subscription manager
+	deviceXAddr string
 }
 
 // SendRequest sends SOAP request to xAddr with digest authenticate
 func (soap SOAP) SendRequest(xaddr string) (mxj.Map, error) {
-	// Create SOAP request
-	request := soap.createRequest()
-	// Make sure URL valid and add authentication in xAddr
+	return soap.SendRequestContext(context.Background(), xaddr)
+}
+
+// SendRequestContext sends SOAP request to xAddr with the credentials sent
+// as selected by AuthMode. The request, including reading the response body,
+// is aborted when ctx is cancelled or its deadline expires.
+func (soap SOAP) SendRequestContext(ctx context.Context, xaddr string) (mxj.Map, error) {
+	responseBody, err := soap.sendContext(ctx, xaddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse XML to map
+	return mxj.NewMapXml(responseBody)
+}
+
+// Call sends SOAP request to xAddr and decodes the response into response
+func (soap SOAP) Call(xaddr string, response interface{}) error {
+	return soap.CallContext(context.Background(), xaddr, response)
+}
+
+// CallContext sends SOAP request to xAddr like SendRequestContext, and
+// decodes the first element of the response body into response with
+// encoding/xml. When response is a struct with an XMLName field, the name
+// and namespace of the element are checked against it.
+func (soap SOAP) CallContext(ctx context.Context, xaddr string, response interface{}) error {
+	responseBody, err := soap.sendContext(ctx, xaddr)
+	if err != nil {
+		return err
+	}
+
+	return decodeResponse(responseBody, response)
+}
+
+// sendContext sends the request and returns the body of the response
+func (soap SOAP) sendContext(ctx context.Context, xaddr string) ([]byte, error) {
+	// Make sure URL valid
 	urlXAddr, err := url.Parse(xaddr)
 	if err != nil {
 		return nil, err
 	}
 
-	if soap.User != "" {
-		urlXAddr.User = url.UserPassword(soap.User, soap.Password)
+	client := soap.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	if soap.User == "" {
+		return soap.send(ctx, client, urlXAddr)
 	}
-	if !soap.NoDebug {
-		glog.Info(request)
+	return soap.sendAuthenticated(ctx, client, urlXAddr)
+}
+
+// send makes a single attempt of the SOAP request. The envelopes are logged
+// redacted, unless NoDebug is set or the client has neither Logger nor
+// Trace, and the attempt is reported to the client's Trace.
+func (soap SOAP) send(ctx context.Context, client *Client, xAddr *url.URL) ([]byte, error) {
+	// Logins are limited by the budget of the context, if any
+	budget := loginBudgetOf(ctx)
+	if soap.User != "" && !budget.take() {
+		return nil, ErrAttemptLimit
+	}
+
+	// Create SOAP request
+	request := soap.createRequest()
+	logger := client.logger()
+
+	trace := client.Trace
+	traced := trace != nil && (trace.RequestStart != nil || trace.RequestDone != nil)
+
+	// Redacting the envelopes is costly for large messages, they are only
+	// redacted when a logger or a trace hook will see them
+	redact := !soap.NoDebug && (client.Logger != nil || traced)
+
+	traceRequest := TraceRequest{
+		XAddr:     xAddr.String(),
+		Operation: soap.operation(),
+		AuthMode:  soap.AuthMode,
 	}
+	if redact {
+		traceRequest.Envelope = RedactEnvelope(request)
+		logger.Log(LevelDebug, "onvif request",
+			"xaddr", traceRequest.XAddr,
+			"operation", traceRequest.Operation,
+			"auth", soap.AuthMode,
+			"envelope", traceRequest.Envelope)
+	}
+	if trace != nil && trace.RequestStart != nil {
+		if traceCtx := trace.RequestStart(ctx, traceRequest); traceCtx != nil {
+			ctx = traceCtx
+		}
+	}
+
+	start := time.Now()
+	statusCode, responseBody, err := soap.post(ctx, client, xAddr, request)
+	traceResponse := TraceResponse{
+		StatusCode: statusCode,
+		Duration:   time.Since(start),
+		Err:        err,
+	}
+	if redact {
+		traceResponse.Envelope = RedactEnvelope(string(responseBody))
+		logger.Log(LevelDebug, "onvif response",
+			"xaddr", traceRequest.XAddr,
+			"operation", traceRequest.Operation,
+			"status", statusCode,
+			"duration", traceResponse.Duration,
+			"error", err,
+			"envelope", traceResponse.Envelope)
+	}
+	if trace != nil && trace.RequestDone != nil {
+		trace.RequestDone(ctx, traceRequest, traceResponse)
+	}
+
+	if err != nil {
+		// The device didn't see the credentials
+		if soap.User != "" && !isDeviceError(err) {
+			budget.refund()
+		}
+		return nil, err
+	}
+	return responseBody, nil
+}
+
+// post sends the request envelope and reads the response. A response with
+// a fault or an error status is returned with its status and body, and the
+// matching *SOAPFault or *HTTPError.
+func (soap SOAP) post(ctx context.Context, client *Client, xAddr *url.URL, request string) (int, []byte, error) {
 	// Create HTTP request
 	buffer := bytes.NewBuffer([]byte(request))
-	req, err := http.NewRequest("POST", urlXAddr.String(), buffer)
+	req, err := http.NewRequestWithContext(ctx, "POST", xAddr.String(), buffer)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	req.Header.Set("Content-Type", "application/soap+xml")
 	req.Header.Set("Charset", "utf-8")
 
-	// Send request
-	var httpDigestClient = digest.NewTransport(soap.User, soap.Password)
-	resp, err := httpDigestClient.RoundTrip(req)
+	// Send request through the client's transport for this camera
+	var transport http.RoundTripper = client.httpTransport(xAddr)
+	if soap.User != "" {
+		switch soap.AuthMode {
+		case AuthHTTPDigest:
+			req = req.WithContext(digest.WithCredentials(ctx, soap.User, soap.Password))
+			transport = client.roundTripper(xAddr)
+		case AuthHTTPBasic:
+			req.SetBasicAuth(soap.User, soap.Password)
+		}
+	}
+	resp, err := transport.RoundTrip(req)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	responseBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
-	}
-
-	if !soap.NoDebug {
-		glog.Infof("Onvif response: %s", string(responseBody))
-	}
-
-	// Parse XML to map
-	mapXML, err := mxj.NewMapXml(responseBody)
-	if err != nil {
-		return nil, err
+		return resp.StatusCode, responseBody, err
 	}
 
 	// Check if SOAP returns fault
-	fault, _ := mapXML.ValueForPathString("Envelope.Body.Fault.Reason.Text.#text")
-	if fault != "" {
-		return nil, errors.New(fault)
+	if fault := parseSOAPFault(responseBody, resp.StatusCode); fault != nil {
+		return resp.StatusCode, responseBody, fault
 	}
-
-	fault, _ = mapXML.ValueForPathString("Envelope.Body.Fault.faultstring")
-	if fault != "" {
-		return nil, errors.New(fault)
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, responseBody, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(responseBody),
+		}
 	}
 
-	return mapXML, nil
+	return resp.StatusCode, responseBody, nil
 }
 
 func (soap SOAP) createRequest() string {
@@ -103,7 +229,8 @@ func (soap SOAP) createRequest() string {
 	request += ">"
 
 	// Set request header
-	if soap.Action != "" || soap.User != "" {
+	withToken := soap.User != "" && soap.AuthMode.usesToken()
+	if soap.Action != "" || len(soap.Headers) > 0 || withToken {
 		request += "<s:Header>"
 
 		if soap.Action != "" {
@@ -111,7 +238,11 @@ func (soap SOAP) createRequest() string {
 							   xmlns="http://www.w3.org/2005/08/addressing">` + soap.Action + `</Action>`
 		}
 
-		if soap.User != "" {
+		for _, header := range soap.Headers {
+			request += header
+		}
+
+		if withToken {
 			request += soap.createUserToken()
 		}
 
@@ -124,17 +255,26 @@ func (soap SOAP) createRequest() string {
 	// Close request envelope
 	request += "</s:Envelope>"
 
-	// Clean request
+	// Clean request, only the indentation between tags is removed so that
+	// the values are sent as they are
 	request = regexp.MustCompile(`\>\s+\<`).ReplaceAllString(request, "><")
-	request = regexp.MustCompile(`\s+`).ReplaceAllString(request, " ")
 
 	return request
 }
 
 func (soap SOAP) createUserToken() string {
+	if soap.AuthMode == AuthUsernameTokenText {
+		return `<Security s:mustUnderstand="1" xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+			<UsernameToken>
+				<Username>` + xmlEscape(soap.User) + `</Username>
+				<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText">` + xmlEscape(soap.Password) + `</Password>
+			</UsernameToken>
+		</Security>`
+	}
+
 	nonce := uuid.New().String()
 	nonce64 := base64.StdEncoding.EncodeToString(([]byte)(nonce))
-	timestamp := time.Now().Add(soap.TokenAge).UTC().Format(time.RFC3339)
+	timestamp := time.Now().Add(soap.clockOffset + soap.TokenAge).UTC().Format(time.RFC3339)
 	token := string(nonce) + timestamp + soap.Password
 
 	sha := sha1.New()
@@ -144,7 +284,7 @@ func (soap SOAP) createUserToken() string {
 
 	return `<Security s:mustUnderstand="1" xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
   		<UsernameToken>
-    		<Username>` + soap.User + `</Username>
+    		<Username>` + xmlEscape(soap.User) + `</Username>
     		<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">` + shaDigest64 + `</Password>
     		<Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">` + nonce64 + `</Nonce>
     		<Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">` + timestamp + `</Created>
@@ -0,0 +1,163 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendRequestContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	soap := SOAP{Body: "<tds:GetDeviceInformation/>", XMLNs: deviceXMLNs, NoDebug: true}
+	start := time.Now()
+	_, err := soap.SendRequestContext(ctx, server.URL)
+	if err == nil {
+		t.Fatal("expected error from hung server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request returned after %v, deadline not honoured", elapsed)
+	}
+}
+
+func TestSendRequestContextFault(t *testing.T) {
+	const fault = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:ter="http://www.onvif.org/ver10/error">
+	<env:Body>
+		<env:Fault>
+			<env:Code>
+				<env:Value>env:Sender</env:Value>
+				<env:Subcode><env:Value>ter:NotAuthorized</env:Value></env:Subcode>
+			</env:Code>
+			<env:Reason><env:Text xml:lang="en">Sender not Authorized</env:Text></env:Reason>
+		</env:Fault>
+	</env:Body>
+</env:Envelope>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fault))
+	}))
+	defer server.Close()
+
+	soap := SOAP{Body: "<tds:GetDeviceInformation/>", XMLNs: deviceXMLNs, NoDebug: true}
+	_, err := soap.SendRequest(server.URL)
+
+	var soapFault *SOAPFault
+	if !errors.As(err, &soapFault) {
+		t.Fatalf("expected *SOAPFault, got %v", err)
+	}
+	if soapFault.Code != "env:Sender" || len(soapFault.Subcodes) != 1 || soapFault.Subcodes[0] != "ter:NotAuthorized" {
+		t.Errorf("unexpected codes %q %q", soapFault.Code, soapFault.Subcodes)
+	}
+	if soapFault.Reason != "Sender not Authorized" || soapFault.HTTPStatus != http.StatusBadRequest {
+		t.Errorf("unexpected reason %q or status %d", soapFault.Reason, soapFault.HTTPStatus)
+	}
+	if !IsNotAuthorized(err) || IsActionNotSupported(err) {
+		t.Error("fault matched the wrong sentinel")
+	}
+}
+
+func TestSendRequestContextHTTPError(t *testing.T) {
+	for _, test := range []struct {
+		status        int
+		notAuthorized bool
+	}{
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, true},
+		{http.StatusInternalServerError, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte("<html>" + http.StatusText(test.status) + "</html>"))
+		}))
+
+		soap := SOAP{Body: "<tds:GetDeviceInformation/>", XMLNs: deviceXMLNs, NoDebug: true}
+		_, err := soap.SendRequest(server.URL)
+		server.Close()
+
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != test.status {
+			t.Errorf("expected *HTTPError %d, got %v", test.status, err)
+			continue
+		}
+		if IsNotAuthorized(err) != test.notAuthorized {
+			t.Errorf("HTTP %d: IsNotAuthorized = %v, want %v", test.status, !test.notAuthorized, test.notAuthorized)
+		}
+	}
+}
+
+func TestRequestValuesAreEscaped(t *testing.T) {
+	var request []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><CreateUsersResponse xmlns="http://www.onvif.org/ver10/device/wsdl"/></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	user := User{Username: "oper</Username>", Password: `p&ss <w>  "x"`, UserLevel: "Operator"}
+	device := Device{XAddr: server.URL}
+	if err := device.CreateUsers([]User{user}); err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope struct {
+		Users []User `xml:"Body>CreateUsers>User"`
+	}
+	if err := xml.Unmarshal(request, &envelope); err != nil {
+		t.Fatalf("request is not well-formed: %v\n%s", err, request)
+	}
+	if len(envelope.Users) != 1 || envelope.Users[0] != user {
+		t.Errorf("values were altered: %+v", envelope.Users)
+	}
+}
+
+func TestSetNetworkProtocolsRequest(t *testing.T) {
+	var request []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><SetNetworkProtocolsResponse xmlns="http://www.onvif.org/ver10/device/wsdl"/></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	protocols := []NetworkProtocol{{Name: "HTTP", Enabled: true, Port: 80}, {Name: "RTSP<", Enabled: false, Port: 554}}
+	if err := (Device{XAddr: server.URL}).SetNetworkProtocols(protocols); err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope struct {
+		NetworkProtocols []struct {
+			XMLName xml.Name
+			Name    string `xml:"http://www.onvif.org/ver10/schema Name"`
+			Enabled bool   `xml:"http://www.onvif.org/ver10/schema Enabled"`
+			Port    int    `xml:"http://www.onvif.org/ver10/schema Port"`
+		} `xml:"Body>SetNetworkProtocols>NetworkProtocols"`
+	}
+	if err := xml.Unmarshal(request, &envelope); err != nil {
+		t.Fatalf("request is not well-formed: %v\n%s", err, request)
+	}
+	if len(envelope.NetworkProtocols) != len(protocols) {
+		t.Fatalf("got %d protocols in %s", len(envelope.NetworkProtocols), request)
+	}
+	for i, protocol := range envelope.NetworkProtocols {
+		if protocol.XMLName.Space != "http://www.onvif.org/ver10/device/wsdl" {
+			t.Errorf("NetworkProtocols in namespace %q", protocol.XMLName.Space)
+		}
+		if (NetworkProtocol{Name: protocol.Name, Enabled: protocol.Enabled, Port: protocol.Port}) != protocols[i] {
+			t.Errorf("got %+v, want %+v", protocol, protocols[i])
+		}
+	}
+}
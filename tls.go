@@ -0,0 +1,170 @@
+package onvif
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds the TLS handshake with a device
+const tlsHandshakeTimeout = 10 * time.Second
+
+// environmentProxy returns the proxy of the requests, replaced by the tests
+var environmentProxy = http.ProxyFromEnvironment
+
+// ErrCertificatePin is returned when the certificate of a device does not
+// match its pinned fingerprint.
+var ErrCertificatePin = errors.New("onvif: certificate does not match the pinned fingerprint")
+
+// PinCertificate pins the certificate of the device at host, given as
+// "host" or "host:port". The fingerprint is the hex encoded SHA-256 of the
+// DER certificate, colons are allowed. A pinned certificate is trusted
+// without checking its chain or name, which suits the self-signed
+// certificates of most cameras.
+func (client *Client) PinCertificate(host, fingerprint string) error {
+	fingerprint = strings.Replace(strings.TrimSpace(fingerprint), ":", "", -1)
+	sum, err := hex.DecodeString(fingerprint)
+	if err != nil {
+		return err
+	}
+	if len(sum) != sha256.Size {
+		return errors.New("onvif: fingerprint is not a SHA-256 digest")
+	}
+
+	client.mu.Lock()
+	client.pins[strings.ToLower(host)] = sum
+	client.mu.Unlock()
+	return nil
+}
+
+// CertificateFingerprint returns the fingerprint of a certificate in the
+// format accepted by PinCertificate.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// pin returns the pinned fingerprint for addr, looking up "host:port" first
+// and then "host".
+func (client *Client) pin(addr string) []byte {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	addr = strings.ToLower(addr)
+	if sum, ok := client.pins[addr]; ok {
+		return sum
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	return client.pins[host]
+}
+
+// tlsConfig returns the TLS configuration of the client. With a pin, the
+// certificate of the device must match it, whatever its chain or name.
+func (client *Client) tlsConfig(pin []byte) *tls.Config {
+	config := &tls.Config{
+		RootCAs:            client.RootCAs,
+		Certificates:       client.Certificates,
+		InsecureSkipVerify: client.InsecureSkipVerify,
+	}
+
+	if pin != nil {
+		// The pin replaces the verification of the chain
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return ErrCertificatePin
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if !bytes.Equal(sum[:], pin) {
+				return ErrCertificatePin
+			}
+			return nil
+		}
+	}
+
+	return config
+}
+
+// newTransport returns an HTTP transport of the client using config for
+// TLS. The TLS settings are applied by the transport itself, so they hold
+// for the connections tunnelled through the proxy of the environment too.
+func (client *Client) newTransport(config *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:               environmentProxy,
+		DialContext:         client.dialer.DialContext,
+		TLSClientConfig:     config,
+		TLSHandshakeTimeout: tlsHandshakeTimeout,
+		MaxIdleConns:        100,
+		// Cameras are polled concurrently by several goroutines
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// pinnedTransport is the transport of an HTTPS device with a pinned
+// certificate
+type pinnedTransport struct {
+	pin       []byte
+	transport *http.Transport
+}
+
+// httpTransport returns the transport of the requests to xAddr, created on
+// first use. HTTPS devices with a pinned certificate have their own, checking
+// the pin, as the name of a device addressed by IP is not known when its
+// certificate is verified.
+func (client *Client) httpTransport(xAddr *url.URL) *http.Transport {
+	var pin []byte
+	addr := xAddr.Host
+	if xAddr.Scheme == "https" {
+		if xAddr.Port() == "" {
+			addr = net.JoinHostPort(xAddr.Hostname(), "443")
+		}
+		pin = client.pin(addr)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if pin == nil {
+		if client.transport == nil {
+			client.transport = client.newTransport(client.tlsConfig(nil))
+		}
+		return client.transport
+	}
+
+	addr = strings.ToLower(addr)
+	pinned, ok := client.pinned[addr]
+	if !ok || !bytes.Equal(pinned.pin, pin) {
+		if ok {
+			pinned.transport.CloseIdleConnections()
+		}
+		pinned = pinnedTransport{pin: pin, transport: client.newTransport(client.tlsConfig(pin))}
+		client.pinned[addr] = pinned
+	}
+	return pinned.transport
+}
+
+// roundTrip sends req with the transport of its device
+func (client *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	return client.httpTransport(req.URL).RoundTrip(req)
+}
+
+// roundTripperFunc turns a function into an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements the http.RoundTripper interface
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
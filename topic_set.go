@@ -0,0 +1,312 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownTopic is returned by TopicSet.Validate for the topics which the
+// device doesn't have
+var ErrUnknownTopic = errors.New("onvif: unknown topic")
+
+// Namespaces of the elements of the topic set
+const (
+	onvifTopicsNamespace = "http://www.onvif.org/ver10/topics"
+	onvifSchemaNamespace = "http://www.onvif.org/ver10/schema"
+)
+
+// TopicSet lists the event topics of a device, as returned by
+// GetEventProperties
+type TopicSet struct {
+	// Topics are the roots of the topic tree, e.g. tns1:RuleEngine
+	Topics []EventTopic
+
+	// FixedTopicSet tells whether the topics may change while the device
+	// is running
+	FixedTopicSet bool
+
+	TopicNamespaceLocations      []string
+	TopicExpressionDialects      []string
+	MessageContentFilterDialects []string
+}
+
+// EventTopic is a topic of the tree of a TopicSet
+type EventTopic struct {
+	// Name is the local name of the topic, e.g. Motion
+	Name string
+
+	// Path is the topic as used in an EventFilter or in NotificationMessage,
+	// e.g. tns1:RuleEngine/CellMotionDetector/Motion
+	Path string
+
+	// Message describes the notifications of the topic. It is nil for the
+	// topics which only group others.
+	Message *MessageDescription
+
+	Children []EventTopic
+}
+
+// MessageDescription describes the items of the notifications of a topic
+type MessageDescription struct {
+	// IsProperty tells whether the notifications report the state of a
+	// property, sent when it is initialized, changed or deleted
+	IsProperty bool
+
+	Source []SimpleItemDescription
+	Key    []SimpleItemDescription
+	Data   []SimpleItemDescription
+}
+
+// SimpleItemDescription is the name and type of a message item, e.g.
+// IsMotion and xs:boolean
+type SimpleItemDescription struct {
+	Name string `xml:"Name,attr"`
+	Type string `xml:"Type,attr"`
+}
+
+// GetTopicSet fetches the event topics of the device, and the description of
+// their messages
+func (device Device) GetTopicSet() (TopicSet, error) {
+	return device.GetTopicSetContext(context.Background())
+}
+
+// GetTopicSetContext is like GetTopicSet but uses ctx for the request.
+func (device Device) GetTopicSetContext(ctx context.Context) (TopicSet, error) {
+	// create soap
+	soap := SOAP{
+		User:     device.User,
+		Password: device.Password,
+		Client:   device.Client,
+		AuthMode: device.AuthMode,
+		Body:     `<GetEventProperties xmlns="http://www.onvif.org/ver10/events/wsdl"/>`,
+	}
+
+	// send request
+	body, err := soap.sendContext(ctx, device.XAddr)
+	if err != nil {
+		return TopicSet{}, err
+	}
+	return parseTopicSet(body)
+}
+
+// parseTopicSet parses a GetEventPropertiesResponse
+func parseTopicSet(body []byte) (TopicSet, error) {
+	var response struct {
+		XMLName                     xml.Name   `xml:"http://www.onvif.org/ver10/events/wsdl GetEventPropertiesResponse"`
+		TopicNamespaceLocation      []string   `xml:"TopicNamespaceLocation"`
+		FixedTopicSet               bool       `xml:"FixedTopicSet"`
+		TopicSet                    xmlElement `xml:"TopicSet"`
+		TopicExpressionDialect      []string   `xml:"TopicExpressionDialect"`
+		MessageContentFilterDialect []string   `xml:"MessageContentFilterDialect"`
+	}
+	if err := decodeResponse(body, &response); err != nil {
+		return TopicSet{}, err
+	}
+
+	prefixes := namespacePrefixes(body)
+	result := TopicSet{
+		FixedTopicSet:                response.FixedTopicSet,
+		TopicNamespaceLocations:      trimAll(response.TopicNamespaceLocation),
+		TopicExpressionDialects:      trimAll(response.TopicExpressionDialect),
+		MessageContentFilterDialects: trimAll(response.MessageContentFilterDialect),
+	}
+	for _, root := range response.TopicSet.Children {
+		path := root.XMLName.Local
+		if prefix := prefixes[root.XMLName.Space]; prefix != "" {
+			path = prefix + ":" + path
+		}
+		result.Topics = append(result.Topics, parseTopic(root, path))
+	}
+	return result, nil
+}
+
+// parseTopic parses a topic element and its children
+func parseTopic(element xmlElement, path string) EventTopic {
+	topic := EventTopic{Name: element.XMLName.Local, Path: path}
+	for _, child := range element.Children {
+		if child.XMLName.Local == "MessageDescription" && child.XMLName.Space == onvifSchemaNamespace {
+			topic.Message = parseMessageDescription(child)
+			continue
+		}
+		topic.Children = append(topic.Children, parseTopic(child, path+"/"+child.XMLName.Local))
+	}
+	return topic
+}
+
+// parseMessageDescription parses a tt:MessageDescription
+func parseMessageDescription(element xmlElement) *MessageDescription {
+	description := &MessageDescription{}
+	for _, attr := range element.Attrs {
+		if attr.Name.Local == "IsProperty" {
+			value := strings.TrimSpace(attr.Value)
+			description.IsProperty = value == "true" || value == "1"
+		}
+	}
+
+	for _, child := range element.Children {
+		var items *[]SimpleItemDescription
+		switch child.XMLName.Local {
+		case "Source":
+			items = &description.Source
+		case "Key":
+			items = &description.Key
+		case "Data":
+			items = &description.Data
+		default:
+			continue
+		}
+
+		for _, item := range child.Children {
+			if item.XMLName.Local != "SimpleItemDescription" {
+				continue
+			}
+			var description SimpleItemDescription
+			for _, attr := range item.Attrs {
+				switch attr.Name.Local {
+				case "Name":
+					description.Name = attr.Value
+				case "Type":
+					description.Type = attr.Value
+				}
+			}
+			*items = append(*items, description)
+		}
+	}
+	return description
+}
+
+// namespacePrefixes returns the prefixes declared in the document for every
+// namespace. ONVIF topics always use tns1, whatever the prefix declared by
+// the device, as it is the prefix of the topics of the notifications.
+func namespacePrefixes(body []byte) map[string]string {
+	prefixes := map[string]string{onvifTopicsNamespace: "tns1"}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return prefixes
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range element.Attr {
+			if attr.Name.Space != "xmlns" {
+				continue
+			}
+			if _, known := prefixes[attr.Value]; !known {
+				prefixes[attr.Value] = attr.Name.Local
+			}
+		}
+	}
+}
+
+// trimAll trims the spaces around every value
+func trimAll(values []string) []string {
+	for i, value := range values {
+		values[i] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// Events returns the topics of the set which have notifications, each one
+// before its descendants, e.g. to list the events a device supports
+func (topicSet TopicSet) Events() []EventTopic {
+	var events []EventTopic
+	var walk func(topics []EventTopic)
+	walk = func(topics []EventTopic) {
+		for _, topic := range topics {
+			if topic.Message != nil {
+				events = append(events, topic)
+			}
+			walk(topic.Children)
+		}
+	}
+	walk(topicSet.Topics)
+	return events
+}
+
+// Find returns the topic with the given path, e.g.
+// tns1:RuleEngine/CellMotionDetector/Motion
+func (topicSet TopicSet) Find(path string) (EventTopic, bool) {
+	topics := topicSet.Topics
+	for _, segment := range strings.Split(strings.TrimSpace(path), "/") {
+		found := false
+		for _, topic := range topics {
+			if topicSegment(topic) == segment {
+				if topic.Path == strings.TrimSpace(path) {
+					return topic, true
+				}
+				topics, found = topic.Children, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return EventTopic{}, false
+}
+
+// Validate checks that every topic of the TopicExpression of filter, in
+// the ConcreteSet dialect, is in the set. An error matching ErrUnknownTopic
+// is returned for the first one which isn't.
+func (topicSet TopicSet) Validate(filter EventFilter) error {
+	if strings.TrimSpace(filter.TopicExpression) == "" {
+		return nil
+	}
+
+	for _, expression := range strings.Split(filter.TopicExpression, "|") {
+		expression = strings.TrimSpace(expression)
+		subtree := strings.HasSuffix(expression, "//.")
+		segments := strings.Split(strings.TrimSuffix(expression, "//."), "/")
+		if !matchTopics(topicSet.Topics, segments, subtree) {
+			return fmt.Errorf("%w: %s", ErrUnknownTopic, expression)
+		}
+	}
+	return nil
+}
+
+// matchTopics reports whether the path of segments, where * matches any
+// topic, leads to a topic with notifications, or to any topic when subtree
+// is set
+func matchTopics(topics []EventTopic, segments []string, subtree bool) bool {
+	for _, topic := range topics {
+		if !matchSegment(segments[0], topicSegment(topic)) {
+			continue
+		}
+		if len(segments) > 1 {
+			if matchTopics(topic.Children, segments[1:], subtree) {
+				return true
+			}
+			continue
+		}
+		if subtree || topic.Message != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegment reports whether the segment of a topic expression matches
+// the segment of a topic. * matches any local name, prefix:* any name with
+// the prefix.
+func matchSegment(pattern, segment string) bool {
+	if pattern == segment || pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, ":*") {
+		return strings.HasPrefix(segment, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+// topicSegment returns the last segment of the path of topic, e.g.
+// tns1:RuleEngine or Motion
+func topicSegment(topic EventTopic) string {
+	return topic.Path[strings.LastIndex(topic.Path, "/")+1:]
+}
@@ -0,0 +1,134 @@
+package onvif
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testEventProperties = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tev="http://www.onvif.org/ver10/events/wsdl" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:wstop="http://docs.oasis-open.org/wsn/t-1" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:ns1="http://www.onvif.org/ver10/topics" xmlns:tnsaxis="http://www.axis.com/2009/event/topics">
+	<env:Body>
+		<tev:GetEventPropertiesResponse>
+			<tev:TopicNamespaceLocation>http://www.onvif.org/onvif/ver10/topics/topicns.xml</tev:TopicNamespaceLocation>
+			<wsnt:FixedTopicSet>true</wsnt:FixedTopicSet>
+			<wstop:TopicSet>
+				<ns1:RuleEngine wstop:topic="false">
+					<CellMotionDetector wstop:topic="false">
+						<Motion wstop:topic="true">
+							<tt:MessageDescription IsProperty="true">
+								<tt:Source>
+									<tt:SimpleItemDescription Name="VideoSourceConfigurationToken" Type="tt:ReferenceToken"/>
+									<tt:SimpleItemDescription Name="Rule" Type="xs:string"/>
+								</tt:Source>
+								<tt:Data>
+									<tt:SimpleItemDescription Name="IsMotion" Type="xs:boolean"/>
+								</tt:Data>
+							</tt:MessageDescription>
+						</Motion>
+					</CellMotionDetector>
+				</ns1:RuleEngine>
+				<ns1:Device wstop:topic="false">
+					<Trigger>
+						<DigitalInput wstop:topic="true">
+							<tt:MessageDescription IsProperty="1">
+								<tt:Source><tt:SimpleItemDescription Name="InputToken" Type="tt:ReferenceToken"/></tt:Source>
+								<tt:Data><tt:SimpleItemDescription Name="LogicalState" Type="xs:boolean"/></tt:Data>
+							</tt:MessageDescription>
+						</DigitalInput>
+					</Trigger>
+				</ns1:Device>
+				<tnsaxis:CameraApplicationPlatform>
+					<VMD>
+						<Camera1Profile1 wstop:topic="true">
+							<tt:MessageDescription>
+								<tt:Key><tt:SimpleItemDescription Name="Id" Type="xs:int"/></tt:Key>
+								<tt:Data><tt:SimpleItemDescription Name="active" Type="xs:boolean"/></tt:Data>
+							</tt:MessageDescription>
+						</Camera1Profile1>
+					</VMD>
+				</tnsaxis:CameraApplicationPlatform>
+			</wstop:TopicSet>
+			<wsnt:TopicExpressionDialect>http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet</wsnt:TopicExpressionDialect>
+			<tev:MessageContentFilterDialect>http://www.onvif.org/ver10/tev/messageContentFilter/ItemFilter</tev:MessageContentFilterDialect>
+		</tev:GetEventPropertiesResponse>
+	</env:Body>
+</env:Envelope>`
+
+func TestGetTopicSet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), "GetEventProperties") {
+			t.Errorf("unexpected request %s", body)
+		}
+		w.Write([]byte(testEventProperties))
+	}))
+	defer server.Close()
+
+	topicSet, err := Device{XAddr: server.URL}.GetTopicSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !topicSet.FixedTopicSet || len(topicSet.TopicExpressionDialects) != 1 || topicSet.TopicExpressionDialects[0] != TopicExpressionConcreteSet ||
+		len(topicSet.MessageContentFilterDialects) != 1 || topicSet.MessageContentFilterDialects[0] != MessageContentItemFilter {
+		t.Errorf("unexpected properties %+v", topicSet)
+	}
+
+	var paths []string
+	for _, topic := range topicSet.Events() {
+		paths = append(paths, topic.Path)
+	}
+	want := []string{
+		"tns1:RuleEngine/CellMotionDetector/Motion",
+		"tns1:Device/Trigger/DigitalInput",
+		"tnsaxis:CameraApplicationPlatform/VMD/Camera1Profile1",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("got events %q, want %q", paths, want)
+	}
+
+	motion, ok := topicSet.Find("tns1:RuleEngine/CellMotionDetector/Motion")
+	if !ok {
+		t.Fatal("motion topic not found")
+	}
+	message := motion.Message
+	if motion.Name != "Motion" || !message.IsProperty || len(message.Source) != 2 || len(message.Key) != 0 ||
+		len(message.Data) != 1 || message.Data[0] != (SimpleItemDescription{Name: "IsMotion", Type: "xs:boolean"}) {
+		t.Errorf("unexpected motion topic %+v %+v", motion, message)
+	}
+	if input, ok := topicSet.Find("tns1:Device/Trigger/DigitalInput"); !ok || !input.Message.IsProperty {
+		t.Errorf("unexpected digital input topic %+v", input)
+	}
+	if vmd, ok := topicSet.Find("tnsaxis:CameraApplicationPlatform/VMD"); !ok || vmd.Message != nil || len(vmd.Children) != 1 {
+		t.Errorf("unexpected VMD topic %+v", vmd)
+	}
+	if _, ok := topicSet.Find("tns1:RuleEngine/CellMotionDetector/Tamper"); ok {
+		t.Error("unknown topic found")
+	}
+
+	for _, test := range []struct {
+		expression string
+		valid      bool
+	}{
+		{"", true},
+		{"tns1:RuleEngine/CellMotionDetector/Motion", true},
+		{"tns1:RuleEngine/CellMotionDetector/Motion|tns1:Device/Trigger/DigitalInput", true},
+		{"tns1:RuleEngine//.", true},
+		{"tns1:Device/*/DigitalInput", true},
+		{"tnsaxis:*/VMD/Camera1Profile1", true},
+		{"tns1:RuleEngine/CellMotionDetector", false},
+		{"tns1:RuleEngine/CellMotionDetector/Motion|tns1:VideoSource/MotionAlarm", false},
+		{"tns1:VideoSource//.", false},
+	} {
+		err := topicSet.Validate(EventFilter{TopicExpression: test.expression})
+		if test.valid && err != nil {
+			t.Errorf("%q: %v", test.expression, err)
+		}
+		if !test.valid && !errors.Is(err, ErrUnknownTopic) {
+			t.Errorf("%q: got %v, want ErrUnknownTopic", test.expression, err)
+		}
+	}
+}
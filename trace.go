@@ -0,0 +1,63 @@
+package onvif
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// Trace holds hooks called around every HTTP request sent by a client,
+// including the attempts made while negotiating the authentication mode.
+// They can be used to feed metrics or tracing spans. Any hook may be nil,
+// and hooks may be called concurrently.
+type Trace struct {
+	// RequestStart is called before the request is sent. The returned
+	// context, if not nil, is used for the request and is passed to
+	// RequestDone, so that a span can be carried from one to the other.
+	RequestStart func(ctx context.Context, request TraceRequest) context.Context
+
+	// RequestDone is called when the response has been read, or when the
+	// request failed.
+	RequestDone func(ctx context.Context, request TraceRequest, response TraceResponse)
+}
+
+// TraceRequest describes a SOAP request given to the Trace hooks
+type TraceRequest struct {
+	// XAddr is the address the request is sent to
+	XAddr string
+	// Operation is the name of the first element of the body, such as
+	// GetDeviceInformation
+	Operation string
+	// AuthMode is the mode the credentials are sent with
+	AuthMode AuthMode
+	// Envelope is the redacted SOAP envelope, it is empty when the
+	// request has NoDebug set
+	Envelope string
+}
+
+// TraceResponse describes the outcome of a SOAP request given to the Trace
+// hooks
+type TraceResponse struct {
+	// StatusCode is the HTTP status of the response, or 0 when none was
+	// received
+	StatusCode int
+	// Envelope is the redacted body of the response, it is empty when the
+	// request has NoDebug set
+	Envelope string
+	// Duration is the time from sending the request to reading the response
+	Duration time.Duration
+	// Err is the error returned for the request, such as a *SOAPFault
+	Err error
+}
+
+// operationName matches the first element of a SOAP body
+var operationName = regexp.MustCompile(`<\s*(?:[\w.-]+:)?([\w.-]+)`)
+
+// operation returns the name of the first element of the body of the request
+func (soap SOAP) operation() string {
+	match := operationName.FindStringSubmatch(soap.Body)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -1,10 +1,13 @@
 package onvif
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var testDevice = Device{
@@ -13,33 +16,11 @@ var testDevice = Device{
 	Password: "Admin123",
 }
 
-func interfaceToString(src interface{}) string {
-	str, _ := src.(string)
-	return str
-}
-
-func interfaceToBool(src interface{}) bool {
-	strBool := interfaceToString(src)
-	return strings.ToLower(strBool) == "true"
-}
-
-func interfaceToInt(src interface{}) int {
-	strNumber := interfaceToString(src)
-	number, _ := strconv.Atoi(strNumber)
-	return number
-}
-
 func prettyJSON(src interface{}) string {
 	result, _ := json.MarshalIndent(&src, "", "    ")
 	return string(result)
 }
 
-func interfaceToFloat64(src interface{}) float64 {
-	strNumber := interfaceToString(src)
-	number, _ := strconv.ParseFloat(strNumber, 64)
-	return number
-}
-
 func intToString(src int) string {
 	strInt := strconv.Itoa(src)
 	return strInt
@@ -50,6 +31,30 @@ func float64ToString(src float64) string {
 	return strFloat64
 }
 
+// xmlEscape escapes a value, so that it can be put in the text or in an
+// attribute of an XML request
+func xmlEscape(src string) string {
+	var buffer bytes.Buffer
+	xml.EscapeText(&buffer, []byte(src))
+	return buffer.String()
+}
+
+// xsdDuration formats d as an xs:duration in whole seconds, e.g. PT10S
+func xsdDuration(d time.Duration) string {
+	return "PT" + strconv.FormatInt(int64(d/time.Second), 10) + "S"
+}
+
+// parseDateTime parses an xs:dateTime. Times without a time zone are taken
+// as UTC, as ONVIF devices send their times in UTC.
+func parseDateTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		parsed, err = time.Parse("2006-01-02T15:04:05.999999999", value)
+	}
+	return parsed, err
+}
+
 func boolToString(src bool) string {
 	if src {
 		return "true"
@@ -59,6 +64,8 @@ func boolToString(src bool) string {
 }
 
 // kiem tra co phai loi chung thuc hay khong
+//
+// Deprecated: use IsNotAuthorized on the returned error instead.
 func CheckAuthorizedError(msg string) bool {
 	msg = strings.ToLower(msg)
 	return strings.Index(msg, "authorized") != -1